@@ -0,0 +1,109 @@
+package migrate
+
+import (
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// BatchModule is a module to migrate as part of a batch
+type BatchModule struct {
+	Path   string
+	Config Config
+}
+
+// NewBatch prepares migrations for many modules. All modules share one set of files so that
+// edits to the same file from different modules, such as remote state updates, are combined.
+func NewBatch(modules []BatchModule) (*Batch, hcl.Diagnostics) {
+	batch := &Batch{
+		paths:      make([]string, 0, len(modules)),
+		migrations: make([]*Migration, 0, len(modules)),
+	}
+
+	var diags hcl.Diagnostics
+	var shared *configwrite.Writer
+
+	for _, module := range modules {
+		writer, wDiags := configwrite.New(module.Path)
+		diags = append(diags, wDiags...)
+		if writer == nil {
+			continue
+		}
+
+		if shared == nil {
+			shared = writer
+		} else {
+			writer.ShareFiles(shared)
+		}
+
+		migration, mDiags := newMigration(writer, module.Config)
+		diags = append(diags, mDiags...)
+
+		batch.paths = append(batch.paths, module.Path)
+		batch.migrations = append(batch.migrations, migration)
+	}
+
+	return batch, diags
+}
+
+// Batch is a set of module migrations that are applied together
+type Batch struct {
+	paths      []string
+	migrations []*Migration
+}
+
+// BatchResult is the outcome of migrating one module in a batch
+type BatchResult struct {
+	Path        string
+	Changes     configwrite.Changes
+	Diagnostics hcl.Diagnostics
+	// Requirements are the version constraints that terraform must satisfy to initialize the module
+	Requirements []terraform.Requirement
+}
+
+// Changes runs the steps for every module and then updates remote state data sources for all modules.
+// It returns the combined changes along with the changes and diagnostics for each module.
+func (b *Batch) Changes() (configwrite.Changes, []BatchResult) {
+	results := make([]BatchResult, len(b.migrations))
+
+	for i, migration := range b.migrations {
+		changes, diags := migration.steps.Changes()
+		results[i] = BatchResult{
+			Path:         b.paths[i],
+			Changes:      changes,
+			Diagnostics:  diags,
+			Requirements: migration.TerraformRequirements(),
+		}
+	}
+
+	for i, migration := range b.migrations {
+		changes, diags := migration.remoteState.Changes()
+		results[i].Diagnostics = append(results[i].Diagnostics, diags...)
+		for path, change := range changes {
+			if err := results[i].Changes.Add(path, change); err != nil {
+				results[i].Diagnostics = append(results[i].Diagnostics, conflictDiagnostic(path, err))
+			}
+		}
+	}
+
+	all := make(configwrite.Changes)
+	for i, result := range results {
+		results[i].Diagnostics = append(results[i].Diagnostics, b.migrations[i].format(result.Changes)...)
+		for path, change := range result.Changes {
+			if err := all.Add(path, change); err != nil {
+				results[i].Diagnostics = append(results[i].Diagnostics, conflictDiagnostic(path, err))
+			}
+		}
+	}
+
+	return all, results
+}
+
+func conflictDiagnostic(path string, err error) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Conflicting changes",
+		Detail:   err.Error(),
+		Subject:  &hcl.Range{Filename: path},
+	}
+}
@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// CheckResult reports whether a module is ready to run in Terraform Cloud
+type CheckResult struct {
+	Path string
+	// Incomplete lists the steps whose changes have not been made
+	Incomplete configwrite.Steps
+	// Issues are problems that the migration steps cannot fix automatically
+	Issues hcl.Diagnostics
+}
+
+// Ready returns true if every step is complete and no issues are errors
+func (r *CheckResult) Ready() bool {
+	return len(r.Incomplete) == 0 && !r.Issues.HasErrors()
+}
+
+// Check reports the steps that are incomplete for a module and any blocking issues without changing files
+func Check(path string, config Config) (*CheckResult, hcl.Diagnostics) {
+	writer, diags := configwrite.New(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	result := &CheckResult{
+		Path:       path,
+		Incomplete: make(configwrite.Steps, 0),
+		Issues:     writer.Issues(),
+	}
+
+	migration, mDiags := newMigration(writer, config)
+	diags = append(diags, mDiags...)
+	if mDiags.HasErrors() {
+		return nil, diags
+	}
+
+	for _, step := range migration.Steps() {
+		complete, sDiags := configwrite.Complete(step)
+		diags = append(diags, sDiags...)
+
+		if !complete {
+			result.Incomplete = append(result.Incomplete, step)
+		}
+	}
+
+	return result, diags
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+func NewBatchCommand(ui cli.Ui) cli.Command {
+	bc := &BatchCommand{
+		Config: &BatchCommandConfig{},
+		Ui:     ui,
+		Flags:  flag.NewFlagSet("batch", flag.ContinueOnError),
+	}
+
+	bc.Flags.SortFlags = false
+	c := bc.Config
+	bc.Flags.StringVar(&c.TerraformBin, "terraform-bin", "", "The terraform binary to run, checked against each module's required_version before it runs (default: terraform on PATH)")
+	bc.Flags.BoolVar(&c.ForceCopy, "force-copy", false, "Copy state without prompting by passing -force-copy and -input=false to 'terraform init', e.g. in CI.")
+	bc.Flags.BoolVar(&c.NoInit, "no-init", false, "Disable calling 'terraform init' in each module before and after updating configuration to copy state.")
+	bc.Flags.BoolVar(&c.NoFormat, "no-format", false, "Keep the existing formatting of lines that are not edited instead of formatting changed files.")
+	bc.Flags.BoolVar(&c.DryRun, "dry-run", false, "Print a diff of the proposed changes without writing files or calling 'terraform init'.")
+
+	return bc
+}
+
+// BatchCommand migrates every module listed in a manifest
+type BatchCommand struct {
+	Flags  *flag.FlagSet
+	Config *BatchCommandConfig
+	Ui     cli.Ui
+}
+
+type BatchCommandConfig struct {
+	TerraformBin string
+	ForceCopy    bool
+	NoInit       bool
+	NoFormat     bool
+	DryRun       bool
+}
+
+func (c *BatchCommand) Run(args []string) int {
+	if err := c.Flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.Flags.Args()) != 1 {
+		c.Ui.Error("manifest path is required")
+		return 1
+	}
+
+	path := c.Flags.Args()[0]
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to resolve path: %s", path))
+		return 1
+	}
+
+	manifest, diags := ReadManifest(abspath)
+	if diags.HasErrors() {
+		printDiags(c.Ui, diags)
+		return 1
+	}
+
+	modules := manifest.BatchModules()
+	for i, module := range modules {
+		modules[i].Config.NoFormat = c.Config.NoFormat
+
+		if err := validateBackend(module.Config.Backend); err != nil {
+			c.Ui.Error(fmt.Sprintf("%s: %v", module.Path, err))
+			return 1
+		}
+	}
+
+	c.Ui.Info(fmt.Sprintf("Upgrading %d Terraform modules from %s", len(modules), abspath))
+
+	batch, diags := migrate.NewBatch(modules)
+	if diags.HasErrors() {
+		printDiags(c.Ui, diags)
+		return 1
+	}
+
+	changes, results := batch.Changes()
+	failed := c.printSummary(results)
+	if failed {
+		c.Ui.Error("One or more modules returned errors. No files were written.")
+		return 1
+	}
+
+	// terraform is checked before it runs so that a binary that does not satisfy a module's requirements cannot
+	// upgrade its state
+	bin := c.Config.TerraformBin
+	if !c.Config.NoInit {
+		path, v, diags := checkTerraform(c.Config.TerraformBin, c.Config.DryRun)
+		printDiags(c.Ui, diags)
+		if diags.HasErrors() {
+			return 1
+		}
+
+		bin = path
+		if v != nil {
+			c.Ui.Info(fmt.Sprintf("Using Terraform %s (%s)", v, bin))
+			if c.checkVersion(v, results) {
+				c.Ui.Error("One or more modules require a different Terraform version. No files were written.")
+				return 1
+			}
+		}
+	}
+
+	if c.Config.DryRun {
+		if len(changes) == 0 {
+			return 0
+		}
+		return printDiff(c.Ui, changes)
+	}
+
+	// workspaces are selected with TF_WORKSPACE when state is copied without input. The manifest cannot map
+	// workspaces, so modules whose workspaces cannot be migrated by prefix are rejected before any module is
+	// initialized if their workspaces can be read without terraform, and otherwise before any files are written.
+	workspaces := newBatchWorkspaces(modules)
+	if !c.Config.NoInit {
+		if c.Config.ForceCopy {
+			if code := c.selectWorkspaces(bin, workspaces, false); code != 0 {
+				return code
+			}
+		}
+
+		c.Ui.Info("Running 'terraform init' in each module prior to updating backends")
+		for _, module := range modules {
+			options := terraform.InitOptions{
+				BackendConfig: module.Config.BackendConfig,
+				NoInput:       c.Config.ForceCopy,
+			}
+			if _, code := terraformInit(c.Ui, bin, module.Path, os.Stdout, options); code != 0 {
+				return code
+			}
+		}
+
+		if c.Config.ForceCopy {
+			if code := c.selectWorkspaces(bin, workspaces, true); code != 0 {
+				return code
+			}
+		}
+	}
+
+	journal, err := configwrite.NewJournal(changes)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to record journal: %v", err))
+		return 1
+	}
+
+	if _, err := journal.Save(filepath.Dir(abspath)); err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to save journal: %v", err))
+		return 1
+	}
+
+	if err := changes.WriteFiles(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if !c.Config.NoInit {
+		c.Ui.Info("Running 'terraform init' in each module to copy state")
+		if !c.Config.ForceCopy {
+			c.Ui.Info("When prompted, type 'yes' to confirm")
+		}
+
+		for i, module := range modules {
+			options := terraform.InitOptions{
+				ForceCopy: c.Config.ForceCopy,
+				NoInput:   c.Config.ForceCopy,
+				Workspace: workspaces[i].name,
+			}
+			if _, code := terraformInit(c.Ui, bin, module.Path, os.Stdout, options); code != 0 {
+				return code
+			}
+		}
+	}
+
+	c.Ui.Info(fmt.Sprintf("Migrated %d modules!", len(modules)))
+	c.Ui.Info(fmt.Sprintf("Run 'terraform-cloud-migrate rollback %s' to restore the previous configuration.", filepath.Dir(abspath)))
+
+	return 0
+}
+
+// batchWorkspace is the workspace to select in a module's new backend, which 'terraform init' cannot prompt for
+type batchWorkspace struct {
+	module   migrate.BatchModule
+	name     string
+	selected bool
+}
+
+func newBatchWorkspaces(modules []migrate.BatchModule) []*batchWorkspace {
+	workspaces := make([]*batchWorkspace, len(modules))
+	for i, module := range modules {
+		workspaces[i] = &batchWorkspace{module: module}
+	}
+	return workspaces
+}
+
+// selectWorkspaces finds the workspace to select in each module that does not have one yet and reports the problems
+// of every module before returning. Modules whose workspaces can only be listed by running terraform, which requires
+// the module to be initialized, are skipped unless list is set.
+func (c *BatchCommand) selectWorkspaces(bin string, workspaces []*batchWorkspace, list bool) int {
+	code := 0
+
+	for _, workspace := range workspaces {
+		if workspace.selected {
+			continue
+		}
+
+		module := workspace.module
+		if !list {
+			writer, diags := moduleWriter(module.Path, module.Config.BackendConfig)
+			if !diags.HasErrors() && writer.LocalBackend() == nil {
+				continue
+			}
+		}
+
+		name, mCode := c.newWorkspace(bin, module)
+		if mCode != 0 {
+			code = mCode
+			continue
+		}
+
+		workspace.name = name
+		workspace.selected = true
+	}
+
+	if code != 0 {
+		c.Ui.Error("One or more modules have workspaces that cannot be migrated by batch. Migrate them with run instead. No files were written.")
+	}
+
+	return code
+}
+
+// newWorkspace returns the workspace to select in the module's new backend
+func (c *BatchCommand) newWorkspace(bin string, module migrate.BatchModule) (string, int) {
+	workspaces := module.Config.Backend.Workspaces
+	discovered, err := discoverWorkspaces(&terraform.Runner{Bin: bin, Dir: module.Path}, module.Config.BackendConfig, true)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("%s: failed to discover workspaces: %v", module.Path, err))
+		return "", 1
+	}
+
+	pairs, diags := workspacePairs(discovered, workspaces)
+	if len(diags) > 0 {
+		c.Ui.Output(fmt.Sprintf("%s: workspaces", module.Path))
+		printDiags(c.Ui, diags)
+	}
+	if diags.HasErrors() {
+		return "", 1
+	}
+
+	return newWorkspace(module.Path, pairs, workspaces), 0
+}
+
+// checkVersion prints the requirements of each module that the terraform version does not satisfy and returns true
+// if there are any
+func (c *BatchCommand) checkVersion(v *terraform.Version, results []migrate.BatchResult) bool {
+	failed := false
+
+	for _, result := range results {
+		diags := terraform.CheckVersion(v, result.Requirements)
+		if len(diags) == 0 {
+			continue
+		}
+
+		failed = true
+		c.Ui.Output(fmt.Sprintf("%s: unsupported Terraform version", result.Path))
+		printDiags(c.Ui, diags)
+	}
+
+	return failed
+}
+
+// printSummary prints the changes and diagnostics for each module and returns true if any module has errors
+func (c *BatchCommand) printSummary(results []migrate.BatchResult) bool {
+	failed := false
+
+	for _, result := range results {
+		status := fmt.Sprintf("%d changes", len(result.Changes))
+		if result.Diagnostics.HasErrors() {
+			failed = true
+			status = "failed"
+		}
+
+		c.Ui.Output(fmt.Sprintf("%s: %s", result.Path, status))
+		for _, path := range result.Changes.Paths() {
+			c.Ui.Output("  " + changeSummary(path, result.Changes[path]))
+		}
+
+		printDiags(c.Ui, result.Diagnostics)
+	}
+
+	return failed
+}
+
+func (c *BatchCommand) Help() string {
+	return strings.TrimSpace(`
+Usage: terraform-cloud-migrate batch [MANIFEST] [options]
+  Migrate every Terraform module listed in an HCL manifest to Terraform Cloud
+
+Options:
+` + c.Flags.FlagUsages())
+}
+
+func (c *BatchCommand) Synopsis() string {
+	return "Run Terraform Cloud migration for many modules"
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCommand_newWorkspace(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		workspaces migrate.WorkspaceConfig
+		expected   string
+		code       int
+	}{
+		{
+			name:       "prefix",
+			current:    "stg",
+			workspaces: migrate.WorkspaceConfig{Prefix: "app-"},
+			expected:   "stg",
+		},
+		{
+			name:       "map",
+			current:    "stg",
+			workspaces: migrate.WorkspaceConfig{Prefix: "app-", Map: map[string]string{"stg": "app-staging"}},
+			expected:   "staging",
+		},
+		{
+			name:       "name",
+			current:    "stg",
+			workspaces: migrate.WorkspaceConfig{Name: "app"},
+			code:       1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{
+				"main.tf":                "",
+				".terraform/environment": tc.current,
+				"terraform.tfstate.d/prod/terraform.tfstate": `{"version": 4}`,
+				"terraform.tfstate.d/stg/terraform.tfstate":  `{"version": 4}`,
+			})
+			defer os.RemoveAll(dir)
+
+			module := migrate.BatchModule{
+				Path: dir,
+				Config: migrate.Config{
+					Backend: configwrite.RemoteBackendConfig{Organization: "org", Workspaces: tc.workspaces},
+				},
+			}
+
+			command := NewBatchCommand(cli.NewMockUi()).(*BatchCommand)
+			workspace, code := command.newWorkspace("terraform", module)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, workspace)
+		})
+	}
+}
+
+func TestBatchCommand_selectWorkspaces(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"default/main.tf":           "",
+		"default/terraform.tfstate": `{"version": 4}`,
+		"s3/main.tf":                "terraform {\n  backend \"s3\" {}\n}\n",
+		"stg/main.tf":               "",
+		"stg/terraform.tfstate.d/stg/terraform.tfstate": `{"version": 4}`,
+	})
+	defer os.RemoveAll(dir)
+
+	var modules []migrate.BatchModule
+	for _, name := range []string{"default", "s3", "stg"} {
+		modules = append(modules, migrate.BatchModule{
+			Path: filepath.Join(dir, name),
+			Config: migrate.Config{
+				Backend: configwrite.RemoteBackendConfig{Organization: "org", Workspaces: migrate.WorkspaceConfig{Prefix: "app-"}},
+			},
+		})
+	}
+
+	ui := cli.NewMockUi()
+	command := NewBatchCommand(ui).(*BatchCommand)
+	workspaces := newBatchWorkspaces(modules)
+
+	// terraform is not run, so the s3 module is skipped until it is initialized
+	code := command.selectWorkspaces(filepath.Join(dir, "terraform"), workspaces, false)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, ui.ErrorWriter.String(), "No workspaces other than 'default' found")
+	assert.False(t, workspaces[0].selected)
+	assert.False(t, workspaces[1].selected)
+	assert.True(t, workspaces[2].selected, "modules after a failure are checked")
+	assert.Equal(t, "stg", workspaces[2].name)
+
+	code = command.selectWorkspaces(filepath.Join(dir, "terraform"), workspaces[1:], true)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, ui.ErrorWriter.String(), filepath.Join(dir, "s3")+": failed to discover workspaces")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+func NewCheckCommand(ui cli.Ui) cli.Command {
+	cc := &CheckCommand{
+		Config: &CheckCommandConfig{},
+		Ui:     ui,
+	}
+	cc.Flags = checkFlags(cc.Config)
+
+	return cc
+}
+
+// checkFlags returns the check flag set, bound to c
+func checkFlags(c *CheckCommandConfig) *flag.FlagSet {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SortFlags = false
+	flags.StringVarP(&c.ModulesDir, "modules", "m", "", "A directory where other Terraform modules are stored. If set, it will be scanned recursively for terraform_remote_state references.")
+	flags.StringSliceVar(&c.BackendConfig, "backend-config", nil, "Partial configuration for the existing backend, as a file relative to the module or a key=value pair (repeatable)")
+	flags.StringVar(&c.BackendStyle, "backend-style", configwrite.BackendStyleBackend, "Require a 'remote' backend block ('backend') or a 'cloud' block ('cloud')")
+
+	return flags
+}
+
+// CheckCommand reports whether modules are ready for Terraform Cloud without changing them
+type CheckCommand struct {
+	Flags  *flag.FlagSet
+	Config *CheckCommandConfig
+	Ui     cli.Ui
+}
+
+type CheckCommandConfig struct {
+	ModulesDir    string
+	BackendConfig []string
+	BackendStyle  string
+}
+
+func (c *CheckCommand) Run(args []string) int {
+	if err := c.Flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.Flags.Args()) == 0 {
+		c.Ui.Error("at least one module path is required")
+		return 1
+	}
+
+	ready := true
+	for _, path := range c.Flags.Args() {
+		result, diags := c.check(args, path)
+		if diags.HasErrors() {
+			ready = false
+			c.Ui.Output(fmt.Sprintf("%s: not ready", path))
+			printDiags(c.Ui, diags)
+			continue
+		}
+
+		if result.Ready() {
+			c.Ui.Output(fmt.Sprintf("%s: ready", path))
+			printDiags(c.Ui, result.Issues)
+			continue
+		}
+
+		ready = false
+		c.Ui.Output(fmt.Sprintf("%s: not ready", path))
+		for _, step := range result.Incomplete {
+			c.Ui.Output(fmt.Sprintf("  incomplete: %s", step.Name()))
+		}
+		printDiags(c.Ui, result.Issues)
+	}
+
+	if !ready {
+		return 1
+	}
+
+	return 0
+}
+
+// check checks a module with the options from its config file applied. Each module gets a fresh flag set so
+// that one module's config file does not leak into the next.
+func (c *CheckCommand) check(args []string, path string) (*migrate.CheckResult, hcl.Diagnostics) {
+	config := &CheckCommandConfig{}
+	flags := checkFlags(config)
+	if err := flags.Parse(args); err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid options",
+			Detail:   err.Error(),
+		}}
+	}
+
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid module path",
+			Detail:   fmt.Sprintf("Failed to resolve path %s: %v.", path, err),
+		}}
+	}
+
+	// config files are written for run, so options that check does not take are ignored
+	var diags hcl.Diagnostics
+	if file := FindConfigFile(abspath); file != "" {
+		diags = applyConfigFile(file, flags, NewRunCommand(c.Ui).(*RunCommand).Flags)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+	}
+
+	result, cDiags := migrate.Check(path, migrate.Config{
+		Backend: migrate.RemoteBackendConfig{
+			Style: config.BackendStyle,
+		},
+		BackendConfig: config.BackendConfig,
+		ModulesDir:    config.ModulesDir,
+	})
+	return result, append(diags, cDiags...)
+}
+
+func (c *CheckCommand) Help() string {
+	return strings.TrimSpace(`
+Usage: terraform-cloud-migrate check [DIR...] [options]
+  Check whether Terraform modules are ready for Terraform Cloud without changing files.
+  Exits with a non-zero status if any migration steps are incomplete or blocking issues are found.
+
+Options:
+` + c.Flags.FlagUsages())
+}
+
+func (c *CheckCommand) Synopsis() string {
+	return "Check Terraform Cloud migration readiness"
+}
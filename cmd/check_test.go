@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCloudModule = `terraform {
+  cloud {
+    organization = "org"
+    workspaces {
+      name = "app"
+    }
+  }
+}
+`
+
+func TestCheckCommand(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		ConfigFilename: "backend_style = \"cloud\"\norganization = \"org\"\n",
+		"bad/main.tf":  "terraform {\n",
+		"good/main.tf": testCloudModule,
+		"warn/main.tf": testCloudModule + `
+resource "null_resource" "app" {
+  provisioner "local-exec" {
+    command = "../scripts/deploy.sh"
+  }
+}
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	code := NewCheckCommand(ui).Run([]string{
+		filepath.Join(dir, "bad"),
+		filepath.Join(dir, "good"),
+		filepath.Join(dir, "warn"),
+	})
+
+	assert.Equal(t, 1, code)
+
+	output := ui.OutputWriter.String()
+	assert.Contains(t, output, filepath.Join(dir, "bad")+": not ready", "a module that fails to load is reported")
+	assert.Contains(t, output, filepath.Join(dir, "good")+": ready", "modules after a load error are checked with the config file's backend style")
+	assert.Contains(t, output, filepath.Join(dir, "warn")+": ready", "warnings do not make a module not ready")
+	assert.Contains(t, ui.ErrorWriter.String(), "local-exec provisioner uses local files")
+}
+
+func TestCheckCommand_configFile(t *testing.T) {
+	remote := `terraform {
+  backend "remote" {
+    organization = "org"
+    workspaces {
+      name = "app"
+    }
+  }
+}
+`
+
+	dir := testTempDir(t, map[string]string{
+		"cloud/" + ConfigFilename:   `backend_style = "cloud"`,
+		"cloud/main.tf":             remote,
+		"remote/main.tf":            remote,
+		"unknown/" + ConfigFilename: `unknown = true`,
+		"unknown/main.tf":           remote,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		module string
+		code   int
+	}{
+		{module: "cloud", code: 1},
+		{module: "remote", code: 0},
+		{module: "unknown", code: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.module, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			code := NewCheckCommand(ui).Run([]string{filepath.Join(dir, tc.module)})
+			assert.Equal(t, tc.code, code, ui.OutputWriter.String()+ui.ErrorWriter.String())
+		})
+	}
+
+	ui := cli.NewMockUi()
+	code := NewCheckCommand(ui).Run([]string{filepath.Join(dir, "cloud"), filepath.Join(dir, "remote")})
+	assert.Equal(t, 1, code)
+	assert.Contains(t, ui.OutputWriter.String(), filepath.Join(dir, "remote")+": ready", "one module's config file does not apply to the next")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	flag "github.com/spf13/pflag"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ConfigFilename is the name of the file that sets default flag values for modules in its directory tree
+const ConfigFilename = ".terraform-cloud-migrate.hcl"
+
+// pathFlags are flags whose values are paths, resolved relative to the config file
+var pathFlags = map[string]bool{
+	"modules":            true,
+	"workspace-map-file": true,
+}
+
+// exclusiveFlags are groups of flags that conflict. If any flag in a group is set on the command line,
+// the config file cannot set the others.
+var exclusiveFlags = [][]string{
+	{"workspace-name", "workspace-prefix", "workspace-tags"},
+}
+
+// FindConfigFile walks up from dir and returns the path of the nearest config file, or an empty string if none exists
+func FindConfigFile(dir string) string {
+	for {
+		path := filepath.Join(dir, ConfigFilename)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ApplyConfigFile sets flags from the attributes in a config file. Attribute names are flag names with
+// underscores in place of dashes. Flags that were set on the command line take precedence.
+func ApplyConfigFile(path string, flags *flag.FlagSet) hcl.Diagnostics {
+	return applyConfigFile(path, flags, flags)
+}
+
+// applyConfigFile sets flags from a config file, skipping attributes that are not in flags but are in known
+func applyConfigFile(path string, flags *flag.FlagSet, known *flag.FlagSet) hcl.Diagnostics {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	attrs, aDiags := file.Body.JustAttributes()
+	diags = append(diags, aDiags...)
+
+	overridden := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		overridden[f.Name] = true
+	})
+	for _, group := range exclusiveFlags {
+		for _, name := range group {
+			if !overridden[name] {
+				continue
+			}
+			for _, other := range group {
+				overridden[other] = true
+			}
+			break
+		}
+	}
+
+	for name, attr := range attrs {
+		flagName := strings.ReplaceAll(name, "_", "-")
+		f := flags.Lookup(flagName)
+		if f == nil && known.Lookup(flagName) != nil {
+			continue
+		}
+		if f == nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   fmt.Sprintf(`An argument named "%s" is not expected here.`, name),
+				Subject:  &attr.NameRange,
+			})
+			continue
+		}
+
+		if f.Hidden || overridden[flagName] {
+			continue
+		}
+
+		value, vDiags := attr.Expr.Value(nil)
+		diags = append(diags, vDiags...)
+		if vDiags.HasErrors() {
+			continue
+		}
+
+		str, err := flagValueString(value)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid argument value",
+				Detail:   fmt.Sprintf(`The value of "%s" is invalid: %v.`, name, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+
+		if pathFlags[flagName] && str != "" && !filepath.IsAbs(str) {
+			str = filepath.Join(filepath.Dir(path), str)
+		}
+
+		if err := flags.Set(flagName, str); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid argument value",
+				Detail:   fmt.Sprintf(`The value of "%s" is invalid: %v.`, name, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	return diags
+}
+
+// flagValueString converts a config value to the string representation accepted by pflag
+func flagValueString(value cty.Value) (string, error) {
+	if value.IsNull() {
+		return "", nil
+	}
+
+	if value.Type().IsListType() || value.Type().IsTupleType() || value.Type().IsSetType() {
+		list, err := convert.Convert(value, cty.List(cty.String))
+		if err != nil {
+			return "", err
+		}
+
+		items := make([]string, 0, list.LengthInt())
+		for _, item := range list.AsValueSlice() {
+			items = append(items, item.AsString())
+		}
+		return strings.Join(items, ","), nil
+	}
+
+	if value.Type().IsMapType() || value.Type().IsObjectType() {
+		m, err := convert.Convert(value, cty.Map(cty.String))
+		if err != nil {
+			return "", err
+		}
+
+		items := make([]string, 0, m.LengthInt())
+		for key, item := range m.AsValueMap() {
+			items = append(items, key+"="+item.AsString())
+		}
+		sort.Strings(items)
+		return strings.Join(items, ","), nil
+	}
+
+	str, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return "", err
+	}
+
+	return str.AsString(), nil
+}
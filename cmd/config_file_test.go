@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTempDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		ConfigFilename:        `organization = "org"`,
+		"modules/app/main.tf": "",
+	})
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, filepath.Join(dir, ConfigFilename), FindConfigFile(filepath.Join(dir, "modules", "app")))
+	assert.Equal(t, filepath.Join(dir, ConfigFilename), FindConfigFile(dir))
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		config   string
+		expected RunCommandConfig
+		diags    []string
+	}{
+		{
+			name: "values",
+			config: `
+				organization       = "org"
+				workspace_prefix   = "app-"
+				workspace_map      = { stg = "app-staging" }
+				backend_config     = ["backend.hcl", "key=app"]
+				tfvars_upload      = true
+				modules            = "modules"
+				workspace_map_file = "/abs/map.hcl"
+			`,
+			expected: RunCommandConfig{
+				Organization:     "org",
+				WorkspacePrefix:  "app-",
+				WorkspaceMap:     map[string]string{"stg": "app-staging"},
+				BackendConfig:    []string{"backend.hcl", "key=app"},
+				TfvarsUpload:     true,
+				ModulesDir:       "modules",
+				WorkspaceMapFile: "/abs/map.hcl",
+			},
+		},
+		{
+			name: "command line",
+			args: []string{"--organization", "cli-org", "--workspace-name", "app"},
+			config: `
+				organization     = "org"
+				workspace_prefix = "app-"
+				hostname         = "tfe.example.com"
+			`,
+			expected: RunCommandConfig{
+				Organization:  "cli-org",
+				WorkspaceName: "app",
+				Hostname:      "tfe.example.com",
+			},
+		},
+		{
+			name:   "unknown",
+			config: `workspace = "app"`,
+			diags:  []string{"Unsupported argument"},
+		},
+		{
+			name:   "invalid",
+			config: `organization = { name = ["org"] }`,
+			diags:  []string{"Invalid argument value"},
+		},
+		{
+			name:   "invalid flag",
+			config: `tfvars_upload = "maybe"`,
+			diags:  []string{"Invalid argument value"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{ConfigFilename: tc.config})
+			defer os.RemoveAll(dir)
+
+			command := NewRunCommand(cli.NewMockUi()).(*RunCommand)
+			if err := command.Flags.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			diags := ApplyConfigFile(filepath.Join(dir, ConfigFilename), command.Flags)
+			assert.Equal(t, tc.diags, diagSummaries(diags))
+			if tc.diags != nil {
+				return
+			}
+
+			if tc.expected.ModulesDir != "" {
+				tc.expected.ModulesDir = filepath.Join(dir, tc.expected.ModulesDir)
+			}
+
+			config := command.Config
+			assert.Equal(t, tc.expected.Organization, config.Organization)
+			assert.Equal(t, tc.expected.WorkspaceName, config.WorkspaceName)
+			assert.Equal(t, tc.expected.WorkspacePrefix, config.WorkspacePrefix)
+			assert.Equal(t, tc.expected.WorkspaceMap, config.WorkspaceMap)
+			assert.Equal(t, tc.expected.BackendConfig, config.BackendConfig)
+			assert.Equal(t, tc.expected.TfvarsUpload, config.TfvarsUpload)
+			assert.Equal(t, tc.expected.ModulesDir, config.ModulesDir)
+			assert.Equal(t, tc.expected.WorkspaceMapFile, config.WorkspaceMapFile)
+			if tc.expected.Hostname != "" {
+				assert.Equal(t, tc.expected.Hostname, config.Hostname)
+			}
+		})
+	}
+}
+
+// diagSummaries returns the summary of each diagnostic, or nil if there are none
+func diagSummaries(diags hcl.Diagnostics) []string {
+	var summaries []string
+	for _, diag := range diags {
+		summaries = append(summaries, diag.Summary)
+	}
+	return summaries
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/bendrucker/terraform-cloud-migrate/tfe"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// jsonResult is the machine-readable output of a migration
+type jsonResult struct {
+	Module      string           `json:"module"`
+	DryRun      bool             `json:"dry_run"`
+	Success     bool             `json:"success"`
+	Changes     []jsonChange     `json:"changes"`
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+	Init        []jsonInit       `json:"init"`
+	Workspaces  []jsonWorkspace  `json:"workspaces"`
+	Variables   []jsonVariable   `json:"variables"`
+	States      []jsonState      `json:"states"`
+	// WorkspaceReplacements are the locations of replaced terraform.workspace references
+	WorkspaceReplacements []jsonRange `json:"workspace_replacements"`
+	// WorkspacePlan maps each existing workspace to the Terraform Cloud workspace it is migrated to
+	WorkspacePlan []jsonWorkspacePlan `json:"workspace_plan"`
+	// Terraform is the terraform binary that was checked before running, if any
+	Terraform *jsonTerraform `json:"terraform,omitempty"`
+}
+
+type jsonChange struct {
+	Path         string           `json:"path"`
+	Destination  string           `json:"destination"`
+	Rename       string           `json:"rename,omitempty"`
+	Delete       bool             `json:"delete,omitempty"`
+	Steps        []jsonStepChange `json:"steps"`
+	BeforeSHA256 string           `json:"before_sha256,omitempty"`
+	AfterSHA256  string           `json:"after_sha256,omitempty"`
+}
+
+type jsonStepChange struct {
+	Step string `json:"step"`
+	Diff string `json:"diff"`
+}
+
+type jsonDiagnostic struct {
+	Severity string     `json:"severity"`
+	Summary  string     `json:"summary"`
+	Detail   string     `json:"detail,omitempty"`
+	Range    *jsonRange `json:"range,omitempty"`
+}
+
+type jsonRange struct {
+	Filename string  `json:"filename"`
+	Start    jsonPos `json:"start"`
+	End      jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+const (
+	initPhaseBefore = "before"
+	initPhaseAfter  = "after"
+
+	initStatusSucceeded = "succeeded"
+	initStatusFailed    = "failed"
+	initStatusSkipped   = "skipped"
+)
+
+type jsonTerraform struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Platform string `json:"platform,omitempty"`
+}
+
+type jsonInit struct {
+	Phase         string `json:"phase"`
+	Status        string `json:"status"`
+	ExitCode      int    `json:"exit_code"`
+	StateMigrated bool   `json:"state_migrated"`
+}
+
+type jsonWorkspace struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Created bool   `json:"created"`
+}
+
+type jsonVariable struct {
+	Key       string `json:"key"`
+	Filename  string `json:"filename"`
+	HCL       bool   `json:"hcl"`
+	Sensitive bool   `json:"sensitive"`
+	Workspace string `json:"workspace,omitempty"`
+}
+
+type jsonWorkspacePlan struct {
+	Workspace string `json:"workspace"`
+	Remote    string `json:"remote,omitempty"`
+	Current   bool   `json:"current"`
+}
+
+type jsonState struct {
+	SourceWorkspace string `json:"source_workspace"`
+	Workspace       string `json:"workspace"`
+	ID              string `json:"id"`
+	Serial          int64  `json:"serial"`
+	Lineage         string `json:"lineage"`
+	Created         bool   `json:"created"`
+}
+
+func newJSONResult(module string, dryRun bool) *jsonResult {
+	return &jsonResult{
+		Module:      module,
+		DryRun:      dryRun,
+		Changes:     make([]jsonChange, 0),
+		Diagnostics: make([]jsonDiagnostic, 0),
+		Init:        make([]jsonInit, 0),
+		Workspaces:  make([]jsonWorkspace, 0),
+		Variables:   make([]jsonVariable, 0),
+		States:      make([]jsonState, 0),
+
+		WorkspaceReplacements: make([]jsonRange, 0),
+		WorkspacePlan:         make([]jsonWorkspacePlan, 0),
+	}
+}
+
+func (r *jsonResult) addChanges(changes configwrite.Changes) error {
+	for _, path := range changes.Paths() {
+		change := changes[path]
+
+		before, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		jc := jsonChange{
+			Path:        path,
+			Destination: change.Destination(path),
+			Rename:      change.Rename,
+			Delete:      change.Delete,
+			Steps:       make([]jsonStepChange, len(change.Steps)),
+		}
+		if !change.Delete {
+			after, err := change.Contents(path)
+			if err != nil {
+				return err
+			}
+			jc.AfterSHA256 = sha256Hex(after)
+		}
+		for i, step := range change.Steps {
+			jc.Steps[i] = jsonStepChange{Step: step.Step, Diff: step.Diff}
+		}
+		if err == nil {
+			jc.BeforeSHA256 = sha256Hex(before)
+		}
+
+		r.Changes = append(r.Changes, jc)
+	}
+
+	return nil
+}
+
+func (r *jsonResult) addDiags(diags hcl.Diagnostics) {
+	for _, diag := range diags {
+		jd := jsonDiagnostic{
+			Summary: diag.Summary,
+			Detail:  diag.Detail,
+		}
+
+		switch diag.Severity {
+		case hcl.DiagError:
+			jd.Severity = "error"
+		case hcl.DiagWarning:
+			jd.Severity = "warning"
+		}
+
+		if diag.Subject != nil {
+			r := newJSONRange(*diag.Subject)
+			jd.Range = &r
+		}
+
+		r.Diagnostics = append(r.Diagnostics, jd)
+	}
+}
+
+func (r *jsonResult) setTerraform(path string, v *terraform.Version) {
+	r.Terraform = &jsonTerraform{
+		Path:     path,
+		Version:  v.Version.String(),
+		Platform: v.Platform,
+	}
+}
+
+func (r *jsonResult) addInit(phase string, status string, code int, migrated bool) {
+	r.Init = append(r.Init, jsonInit{
+		Phase:         phase,
+		Status:        status,
+		ExitCode:      code,
+		StateMigrated: migrated,
+	})
+}
+
+func (r *jsonResult) addWorkspace(ws *tfe.Workspace, created bool) {
+	r.Workspaces = append(r.Workspaces, jsonWorkspace{
+		ID:      ws.ID,
+		Name:    ws.Name,
+		Created: created,
+	})
+}
+
+func (r *jsonResult) addVariables(variables []configwrite.Variable) {
+	for _, variable := range variables {
+		r.Variables = append(r.Variables, jsonVariable{
+			Key:       variable.Key,
+			Filename:  variable.Filename,
+			HCL:       variable.HCL,
+			Sensitive: variable.Sensitive,
+			Workspace: variable.Workspace,
+		})
+	}
+}
+
+func (r *jsonResult) addWorkspacePlan(discovered *state.Workspaces, remotes map[string]string) {
+	for _, workspace := range discovered.All {
+		r.WorkspacePlan = append(r.WorkspacePlan, jsonWorkspacePlan{
+			Workspace: workspace.Name,
+			Remote:    remotes[workspace.Name],
+			Current:   workspace.Name == discovered.Current,
+		})
+	}
+}
+
+func (r *jsonResult) addWorkspaceReplacements(ranges []hcl.Range) {
+	for _, rng := range ranges {
+		r.WorkspaceReplacements = append(r.WorkspaceReplacements, newJSONRange(rng))
+	}
+}
+
+func (r *jsonResult) addState(pair workspacePair, sv *tfe.StateVersion, created bool) {
+	r.States = append(r.States, jsonState{
+		SourceWorkspace: pair.Local,
+		Workspace:       pair.Remote,
+		ID:              sv.ID,
+		Serial:          sv.Serial,
+		Lineage:         sv.Lineage,
+		Created:         created,
+	})
+}
+
+func (r *jsonResult) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func newJSONRange(rng hcl.Range) jsonRange {
+	return jsonRange{
+		Filename: rng.Filename,
+		Start:    newJSONPos(rng.Start),
+		End:      newJSONPos(rng.End),
+	}
+}
+
+func newJSONPos(pos hcl.Pos) jsonPos {
+	return jsonPos{
+		Line:   pos.Line,
+		Column: pos.Column,
+		Byte:   pos.Byte,
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -24,6 +24,18 @@ func main() {
 	}
 
 	app.Commands = map[string]cli.CommandFactory{
+		"batch": func() (cli.Command, error) {
+			return NewBatchCommand(ui), nil
+		},
+		"check": func() (cli.Command, error) {
+			return NewCheckCommand(ui), nil
+		},
+		"plan": func() (cli.Command, error) {
+			return NewPlanCommand(ui), nil
+		},
+		"rollback": func() (cli.Command, error) {
+			return NewRollbackCommand(ui), nil
+		},
 		"run": func() (cli.Command, error) {
 			return NewRunCommand(ui), nil
 		},
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// Manifest describes a set of modules to migrate with the batch command
+type Manifest struct {
+	BackendStyle      string `hcl:"backend_style,optional"`
+	Hostname          string `hcl:"hostname,optional"`
+	Organization      string `hcl:"organization,optional"`
+	WorkspaceVariable string `hcl:"workspace_variable,optional"`
+	TfvarsFilename    string `hcl:"tfvars_filename,optional"`
+	ModulesDir        string `hcl:"modules,optional"`
+	// ExtractWorkspaceLocals writes workspace-keyed locals to a tfvars file for each workspace in every module
+	ExtractWorkspaceLocals bool `hcl:"extract_workspace_locals,optional"`
+
+	Modules []ManifestModule `hcl:"module,block"`
+}
+
+// ManifestModule configures the migration of one module. Unset values are inherited from the manifest.
+type ManifestModule struct {
+	Path              string   `hcl:"path,label"`
+	BackendStyle      string   `hcl:"backend_style,optional"`
+	Hostname          string   `hcl:"hostname,optional"`
+	Organization      string   `hcl:"organization,optional"`
+	WorkspaceName     string   `hcl:"workspace_name,optional"`
+	WorkspacePrefix   string   `hcl:"workspace_prefix,optional"`
+	WorkspaceTags     []string `hcl:"workspace_tags,optional"`
+	WorkspaceVariable string   `hcl:"workspace_variable,optional"`
+	TfvarsFilename    string   `hcl:"tfvars_filename,optional"`
+	BackendConfig     []string `hcl:"backend_config,optional"`
+}
+
+// ReadManifest parses a manifest file. Module paths are resolved relative to the manifest.
+func ReadManifest(path string) (*Manifest, hcl.Diagnostics) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	manifest := &Manifest{
+		BackendStyle:      configwrite.BackendStyleBackend,
+		Hostname:          "app.terraform.io",
+		WorkspaceVariable: "environment",
+		TfvarsFilename:    configwrite.TfvarsAlternateFilename,
+	}
+
+	diags = append(diags, gohcl.DecodeBody(file.Body, nil, manifest)...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	dir := filepath.Dir(path)
+	for i, module := range manifest.Modules {
+		if !filepath.IsAbs(module.Path) {
+			manifest.Modules[i].Path = filepath.Join(dir, module.Path)
+		}
+	}
+
+	if manifest.ModulesDir != "" && !filepath.IsAbs(manifest.ModulesDir) {
+		manifest.ModulesDir = filepath.Join(dir, manifest.ModulesDir)
+	}
+
+	return manifest, diags
+}
+
+// BatchModules returns the batch migration for each module with defaults from the manifest applied
+func (m *Manifest) BatchModules() []migrate.BatchModule {
+	modules := make([]migrate.BatchModule, len(m.Modules))
+
+	for i, module := range m.Modules {
+		modules[i] = migrate.BatchModule{
+			Path: module.Path,
+			Config: migrate.Config{
+				Backend: migrate.RemoteBackendConfig{
+					Style:        stringDefault(module.BackendStyle, m.BackendStyle),
+					Hostname:     stringDefault(module.Hostname, m.Hostname),
+					Organization: stringDefault(module.Organization, m.Organization),
+					Workspaces: migrate.WorkspaceConfig{
+						Name:   module.WorkspaceName,
+						Prefix: module.WorkspacePrefix,
+						Tags:   module.WorkspaceTags,
+					},
+				},
+				WorkspaceVariable: stringDefault(module.WorkspaceVariable, m.WorkspaceVariable),
+				TfvarsFilename:    stringDefault(module.TfvarsFilename, m.TfvarsFilename),
+				BackendConfig:     module.BackendConfig,
+				ModulesDir:        m.ModulesDir,
+
+				// batch does not upload variables
+				ExtractWorkspaceLocals: m.ExtractWorkspaceLocals,
+				WorkspaceTfvarsFiles:   m.ExtractWorkspaceLocals,
+			},
+		}
+	}
+
+	return modules
+}
+
+func stringDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
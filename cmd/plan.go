@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+func NewPlanCommand(ui cli.Ui) cli.Command {
+	rc := NewRunCommand(ui).(*RunCommand)
+	rc.Flags.Init("plan", flag.ContinueOnError)
+	for _, name := range []string{"dry-run", "no-init", "provision", "terraform-version", "execution-mode", "working-directory", "auto-apply", "agent-pool-id", "copy-state", "state-source", "force-copy", "terraform-bin"} {
+		rc.Flags.MarkHidden(name)
+	}
+
+	return &PlanCommand{rc}
+}
+
+// PlanCommand prints the changes that the run command would make without writing them
+type PlanCommand struct {
+	*RunCommand
+}
+
+func (c *PlanCommand) Run(args []string) int {
+	c.Config.DryRun = true
+	return c.RunCommand.Run(args)
+}
+
+func (c *PlanCommand) Help() string {
+	return strings.TrimSpace(`
+Usage: terraform-cloud-migrate plan [DIR] [options]
+  Print a diff of the changes required to migrate a Terraform module to Terraform Cloud
+
+Options:
+` + c.Flags.FlagUsages())
+}
+
+func (c *PlanCommand) Synopsis() string {
+	return "Preview Terraform Cloud migration changes"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/tfe"
+)
+
+// ProvisionConfig holds the workspace settings applied with --provision
+type ProvisionConfig struct {
+	Enabled          bool
+	TerraformVersion string
+	ExecutionMode    string
+	AgentPoolID      string
+	WorkingDirectory string
+	AutoApply        bool
+}
+
+// api creates an API client for the backend's hostname
+func (c *RunCommand) api(backend migrate.RemoteBackendConfig) (*tfe.Client, error) {
+	token, err := tfe.Token(backend.Hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	return tfe.NewClient(backend.Hostname, token), nil
+}
+
+// provision creates or updates the Terraform Cloud workspaces for the module
+func (c *RunCommand) provision(client *tfe.Client, pairs []workspacePair, abspath string, backend migrate.RemoteBackendConfig) int {
+	config := c.Config.Provision
+
+	workingDirectory := config.WorkingDirectory
+	if workingDirectory == "" {
+		var err error
+		workingDirectory, err = repositoryPath(abspath)
+		if err != nil {
+			c.error(err)
+			return 1
+		}
+	}
+
+	options := tfe.WorkspaceOptions{
+		TerraformVersion: config.TerraformVersion,
+		ExecutionMode:    config.ExecutionMode,
+		AgentPoolID:      config.AgentPoolID,
+		WorkingDirectory: workingDirectory,
+		TagNames:         backend.Workspaces.Tags,
+	}
+	if c.Flags.Changed("auto-apply") {
+		options.AutoApply = &config.AutoApply
+	}
+
+	for _, pair := range pairs {
+		options.Name = pair.Remote
+		ws, created, err := client.UpsertWorkspace(backend.Organization, options)
+		if err != nil {
+			c.error(fmt.Errorf("failed to provision workspace '%s': %v", pair.Remote, err))
+			return 1
+		}
+
+		action := "Updated"
+		if created {
+			action = "Created"
+		}
+		c.Ui.Info(fmt.Sprintf("%s workspace %s/%s (%s)", action, backend.Organization, ws.Name, ws.ID))
+
+		if c.result != nil {
+			c.result.addWorkspace(ws, created)
+		}
+	}
+
+	return 0
+}
+
+func validateProvision(config ProvisionConfig, backend migrate.RemoteBackendConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if backend.Organization == "" {
+		return errors.New("--organization is required to provision workspaces")
+	}
+
+	switch config.ExecutionMode {
+	case "", tfe.ExecutionModeRemote, tfe.ExecutionModeLocal, tfe.ExecutionModeAgent:
+	default:
+		return fmt.Errorf("execution mode must be '%s', '%s', or '%s'", tfe.ExecutionModeRemote, tfe.ExecutionModeLocal, tfe.ExecutionModeAgent)
+	}
+
+	if config.ExecutionMode == tfe.ExecutionModeAgent && config.AgentPoolID == "" {
+		return errors.New("--agent-pool-id is required with --execution-mode agent")
+	}
+	if config.ExecutionMode != tfe.ExecutionModeAgent && config.AgentPoolID != "" {
+		return errors.New("--agent-pool-id requires --execution-mode agent")
+	}
+
+	return nil
+}
+
+// repositoryPath returns the path of dir relative to the root of its git repository, which is
+// the working directory Terraform Cloud uses for VCS-driven runs
+func repositoryPath(dir string) (string, error) {
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", fmt.Errorf("%s is not in a git repository, set --working-directory", dir)
+		}
+		root = parent
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+
+	if rel == "." {
+		return "", nil
+	}
+
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProvision(t *testing.T) {
+	backend := migrate.RemoteBackendConfig{Organization: "org"}
+
+	tests := []struct {
+		name    string
+		config  ProvisionConfig
+		backend migrate.RemoteBackendConfig
+		err     string
+	}{
+		{
+			name:    "disabled",
+			config:  ProvisionConfig{ExecutionMode: "agent"},
+			backend: migrate.RemoteBackendConfig{},
+		},
+		{
+			name:    "organization",
+			config:  ProvisionConfig{Enabled: true},
+			backend: migrate.RemoteBackendConfig{},
+			err:     "--organization is required to provision workspaces",
+		},
+		{
+			name:    "execution mode",
+			config:  ProvisionConfig{Enabled: true, ExecutionMode: "vcs"},
+			backend: backend,
+			err:     "execution mode must be 'remote', 'local', or 'agent'",
+		},
+		{
+			name:    "agent",
+			config:  ProvisionConfig{Enabled: true, ExecutionMode: "agent", AgentPoolID: "apool-1"},
+			backend: backend,
+		},
+		{
+			name:    "agent without pool",
+			config:  ProvisionConfig{Enabled: true, ExecutionMode: "agent"},
+			backend: backend,
+			err:     "--agent-pool-id is required with --execution-mode agent",
+		},
+		{
+			name:    "pool without agent",
+			config:  ProvisionConfig{Enabled: true, ExecutionMode: "remote", AgentPoolID: "apool-1"},
+			backend: backend,
+			err:     "--agent-pool-id requires --execution-mode agent",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateProvision(tc.config, tc.backend)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+func NewRollbackCommand(ui cli.Ui) cli.Command {
+	return &RollbackCommand{
+		Ui:    ui,
+		Flags: flag.NewFlagSet("rollback", flag.ContinueOnError),
+	}
+}
+
+// RollbackCommand restores a module's files from the journal written by the most recent run
+type RollbackCommand struct {
+	Flags *flag.FlagSet
+	Ui    cli.Ui
+}
+
+func (c *RollbackCommand) Run(args []string) int {
+	if err := c.Flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.Flags.Args()) != 1 {
+		c.Ui.Error("module path is required")
+		return 1
+	}
+
+	path := c.Flags.Args()[0]
+	abspath, err := filepath.Abs(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to resolve path: %s", path))
+		return 1
+	}
+
+	journal, jpath, err := configwrite.LatestJournal(abspath)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Info(fmt.Sprintf("Rolling back changes from %s", jpath))
+
+	if err := journal.Rollback(); err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to roll back: %v", err))
+		return 1
+	}
+
+	for _, file := range journal.Files {
+		if file.Exists {
+			c.Ui.Output(fmt.Sprintf("restored %s", file.Path))
+		} else {
+			c.Ui.Output(fmt.Sprintf("removed %s", file.Path))
+		}
+	}
+
+	if err := os.Remove(jpath); err != nil {
+		c.Ui.Warn(fmt.Sprintf("failed to remove journal: %v", err))
+	}
+
+	c.Ui.Info("Rollback complete!")
+	c.Ui.Info("State that was already copied to Terraform Cloud is not removed.")
+
+	return 0
+}
+
+func (c *RollbackCommand) Help() string {
+	return strings.TrimSpace(`
+Usage: terraform-cloud-migrate rollback [DIR]
+  Restore a module's files to their state before the most recent migration
+`)
+}
+
+func (c *RollbackCommand) Synopsis() string {
+	return "Undo the most recent Terraform Cloud migration"
+}
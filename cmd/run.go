@@ -1,14 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	migrate "github.com/bendrucker/terraform-cloud-migrate"
 	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/bendrucker/terraform-cloud-migrate/tfe"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/mitchellh/cli"
 
@@ -27,14 +32,44 @@ func NewRunCommand(ui cli.Ui) cli.Command {
 	c := rc.Config
 	rc.Flags.StringVarP(&c.WorkspaceName, "workspace-name", "n", "", "The name of the Terraform Cloud workspace (conflicts with --workspace-prefix)")
 	rc.Flags.StringVarP(&c.WorkspacePrefix, "workspace-prefix", "p", "", "The prefix of the Terraform Cloud workspaces (conflicts with --workspace-name)")
+	rc.Flags.StringSliceVar(&c.WorkspaceTags, "workspace-tags", nil, "Tags that select the Terraform Cloud workspaces (requires --backend-style=cloud)")
+	rc.Flags.StringToStringVar(&c.WorkspaceMap, "workspace-map", nil, "Rename existing workspaces in Terraform Cloud, e.g. stg=app-staging (requires --copy-state and --workspace-prefix or --workspace-tags)")
+	rc.Flags.StringVar(&c.WorkspaceMapFile, "workspace-map-file", "", "An HCL file of existing workspace names mapped to Terraform Cloud workspace names, merged with --workspace-map")
 	rc.Flags.StringVarP(&c.ModulesDir, "modules", "m", "", "A directory where other Terraform modules are stored. If set, it will be scanned recursively for terrafor_remote_state references.")
 	rc.Flags.StringVar(&c.WorkspaceVariable, "workspace-variable", "environment", "Variable that will replace terraform.workspace")
 	rc.Flags.StringVar(&c.TfvarsFilename, "tfvars-filename", configwrite.TfvarsAlternateFilename, "New filename for terraform.tfvars")
-
-	rc.Flags.StringVar(&c.Hostname, "hostname", "app.terraform.io", "Hostname for Terraform Cloud")
+	rc.Flags.BoolVar(&c.TfvarsUpload, "tfvars-upload", false, "Upload values from terraform.tfvars and *.auto.tfvars as workspace variables and delete the files instead of renaming terraform.tfvars")
+	rc.Flags.BoolVar(&c.ExtractWorkspaceLocals, "extract-workspace-locals", false, "Replace locals looked up by terraform.workspace with variables, uploaded to each workspace")
+	rc.Flags.BoolVar(&c.WorkspaceTfvarsFiles, "workspace-tfvars-files", false, "Write the values of extracted locals to <workspace>.tfvars instead of uploading them. Terraform does not load these files automatically.")
+	rc.Flags.StringSliceVar(&c.VarFiles, "var-file", nil, "Additional variable files to upload and delete (requires --tfvars-upload)")
+	rc.Flags.StringVar(&c.SensitiveVariables, "sensitive-variables", "", "Regular expression matching the names of uploaded variables to mark sensitive")
+
+	rc.Flags.StringSliceVar(&c.BackendConfig, "backend-config", nil, "Partial configuration for the existing backend, as a file relative to the module or a key=value pair, passed to 'terraform init' (repeatable)")
+	rc.Flags.BoolVar(&c.ConvertBackendConfig, "convert-backend-config", false, "Convert backend config files to Terraform Cloud settings instead of deleting them")
+	rc.Flags.StringVar(&c.BackendStyle, "backend-style", configwrite.BackendStyleBackend, "Configure Terraform Cloud with a 'remote' backend block ('backend') or a 'cloud' block ('cloud', Terraform 1.1+)")
+	rc.Flags.StringVar(&c.Hostname, "hostname", tfe.DefaultHostname, "Hostname for Terraform Cloud")
 	rc.Flags.StringVar(&c.Organization, "organization", "", "Organization name in Terraform Cloud")
 
+	rc.Flags.StringSliceVar(&c.OnlySteps, "only-step", nil, fmt.Sprintf("Run only these steps (%s)", strings.Join(migrate.StepIDs, ", ")))
+	rc.Flags.StringSliceVar(&c.SkipSteps, "skip-step", nil, "Skip these steps")
+	rc.Flags.BoolVarP(&c.Interactive, "interactive", "i", false, "Show each step's proposed changes and ask whether to apply it")
+
+	rc.Flags.BoolVar(&c.Provision.Enabled, "provision", false, "Create or update the Terraform Cloud workspaces through the API before copying state")
+	rc.Flags.StringVar(&c.Provision.TerraformVersion, "terraform-version", "", "Terraform version for provisioned workspaces")
+	rc.Flags.StringVar(&c.Provision.ExecutionMode, "execution-mode", "", "Execution mode for provisioned workspaces ('remote', 'local', or 'agent')")
+	rc.Flags.StringVar(&c.Provision.AgentPoolID, "agent-pool-id", "", "Agent pool for provisioned workspaces (requires --execution-mode agent)")
+	rc.Flags.StringVar(&c.Provision.WorkingDirectory, "working-directory", "", "Working directory for provisioned workspaces (default: the module's path in its git repository)")
+	rc.Flags.BoolVar(&c.Provision.AutoApply, "auto-apply", false, "Automatically apply successful plans in provisioned workspaces")
+
+	rc.Flags.BoolVar(&c.CopyState, "copy-state", false, "Upload state to Terraform Cloud through the API instead of copying it with an interactive 'terraform init'")
+	rc.Flags.StringVar(&c.StateSource, "state-source", stateSourcePull, "Where to read state for --copy-state: 'pull' runs 'terraform state pull' for each workspace, 'local' reads the local backend's state files")
+
+	rc.Flags.BoolVar(&c.NoFormat, "no-format", false, "Keep the existing formatting of lines that are not edited instead of formatting changed files.")
+	rc.Flags.StringVar(&c.TerraformBin, "terraform-bin", "", "The terraform binary to run, checked against the module's required_version before it runs (default: terraform on PATH)")
+	rc.Flags.BoolVar(&c.ForceCopy, "force-copy", false, "Copy state without prompting by passing -force-copy and -input=false to 'terraform init', e.g. in CI.")
 	rc.Flags.BoolVar(&c.NoInit, "no-init", false, "Disable calling 'terraform init' before and after updating configuration to copy state.")
+	rc.Flags.BoolVar(&c.DryRun, "dry-run", false, "Print a diff of the proposed changes without writing files or calling 'terraform init'.")
+	rc.Flags.BoolVar(&c.JSON, "json", false, "Print a JSON document describing changes, diagnostics, and 'terraform init' results instead of text.")
 
 	return rc
 }
@@ -43,17 +78,44 @@ type RunCommand struct {
 	Flags  *pflag.FlagSet
 	Config *RunCommandConfig
 	Ui     cli.Ui
+
+	// result collects output when --json is set
+	result *jsonResult
+	// bin is the terraform binary, resolved to an absolute path if it was found
+	bin string
 }
 
 type RunCommandConfig struct {
-	Hostname          string
-	Organization      string
-	WorkspaceName     string
-	WorkspacePrefix   string
-	WorkspaceVariable string
-	TfvarsFilename    string
-	ModulesDir        string
-	NoInit            bool
+	BackendStyle           string
+	Hostname               string
+	Organization           string
+	WorkspaceName          string
+	WorkspacePrefix        string
+	WorkspaceTags          []string
+	WorkspaceMap           map[string]string
+	WorkspaceMapFile       string
+	WorkspaceVariable      string
+	TfvarsFilename         string
+	TfvarsUpload           bool
+	ExtractWorkspaceLocals bool
+	WorkspaceTfvarsFiles   bool
+	VarFiles               []string
+	SensitiveVariables     string
+	BackendConfig          []string
+	ConvertBackendConfig   bool
+	ModulesDir             string
+	OnlySteps              []string
+	SkipSteps              []string
+	Interactive            bool
+	Provision              ProvisionConfig
+	CopyState              bool
+	StateSource            string
+	NoFormat               bool
+	TerraformBin           string
+	ForceCopy              bool
+	NoInit                 bool
+	DryRun                 bool
+	JSON                   bool
 }
 
 func (c *RunCommand) Run(args []string) int {
@@ -73,75 +135,354 @@ func (c *RunCommand) Run(args []string) int {
 		return 1
 	}
 
+	// the config file is applied before any output so that it can enable JSON output
+	config := FindConfigFile(abspath)
+	var configDiags hcl.Diagnostics
+	if config != "" {
+		configDiags = ApplyConfigFile(config, c.Flags)
+	}
+
+	if !c.Config.JSON {
+		if config != "" {
+			c.Ui.Info(fmt.Sprintf("Using configuration from %s", config))
+		}
+		if configDiags.HasErrors() {
+			printDiags(c.Ui, configDiags)
+			return 1
+		}
+		return c.run(path, abspath)
+	}
+
+	// in JSON mode, stdout is reserved for the result document
+	ui := c.Ui
+	defer func() { c.Ui = ui }()
+	c.Ui = &cli.BasicUi{
+		Reader:      os.Stdin,
+		Writer:      os.Stderr,
+		ErrorWriter: os.Stderr,
+	}
+
+	c.result = newJSONResult(abspath, c.Config.DryRun)
+	if config != "" {
+		c.Ui.Info(fmt.Sprintf("Using configuration from %s", config))
+	}
+
+	code := 1
+	c.diags(configDiags)
+	if !configDiags.HasErrors() {
+		code = c.run(path, abspath)
+	}
+	c.result.Success = code == 0
+
+	if err := c.result.write(os.Stdout); err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to write JSON: %v", err))
+		return 1
+	}
+
+	return code
+}
+
+func (c *RunCommand) run(path, abspath string) int {
 	c.Ui.Info(fmt.Sprintf("Upgrading Terraform module %s", abspath))
 
-	if c.Config.WorkspaceName == "" && c.Config.WorkspacePrefix == "" {
-		c.Ui.Error("workspace name or prefix is required")
+	workspaceMap, diags := mergeWorkspaceMap(c.Config.WorkspaceMapFile, c.Config.WorkspaceMap)
+	c.diags(diags)
+	if diags.HasErrors() {
+		return 1
+	}
+
+	backend := migrate.RemoteBackendConfig{
+		Style:        c.Config.BackendStyle,
+		Hostname:     c.Config.Hostname,
+		Organization: c.Config.Organization,
+		Workspaces: migrate.WorkspaceConfig{
+			Prefix: c.Config.WorkspacePrefix,
+			Name:   c.Config.WorkspaceName,
+			Tags:   c.Config.WorkspaceTags,
+			Map:    workspaceMap,
+		},
+	}
+
+	if err := validateBackend(backend); err != nil {
+		c.error(err)
+		return 1
+	}
+
+	if c.Config.ConvertBackendConfig && backend.Cloud() {
+		c.error(fmt.Errorf("--convert-backend-config requires --backend-style %s, since 'cloud' blocks do not read backend config files", configwrite.BackendStyleBackend))
 		return 1
 	}
 
-	if c.Config.WorkspaceName != "" && c.Config.WorkspacePrefix != "" {
-		c.Ui.Error("workspace cannot have a name and prefix")
+	if err := validateProvision(c.Config.Provision, backend); err != nil {
+		c.error(err)
 		return 1
 	}
 
-	migration, diags := migrate.New(path, migrate.Config{
-		Backend: migrate.RemoteBackendConfig{
-			Hostname:     c.Config.Hostname,
-			Organization: c.Config.Organization,
-			Workspaces: migrate.WorkspaceConfig{
-				Prefix: c.Config.WorkspacePrefix,
-				Name:   c.Config.WorkspaceName,
+	if err := validateTfvarsUpload(c.Config); err != nil {
+		c.error(err)
+		return 1
+	}
+
+	if err := validateCopyState(c.Config, backend.Workspaces); err != nil {
+		c.error(err)
+		return 1
+	}
+
+	var sensitive *regexp.Regexp
+	if c.Config.SensitiveVariables != "" {
+		var err error
+		if sensitive, err = regexp.Compile(c.Config.SensitiveVariables); err != nil {
+			c.error(fmt.Errorf("invalid --sensitive-variables pattern: %v", err))
+			return 1
+		}
+	}
+
+	steps, err := selectSteps(c.Config.OnlySteps, c.Config.SkipSteps)
+	if err != nil {
+		c.error(err)
+		return 1
+	}
+
+	config := migrate.Config{
+		Backend:                backend,
+		WorkspaceVariable:      c.Config.WorkspaceVariable,
+		TfvarsFilename:         c.Config.TfvarsFilename,
+		TfvarsUpload:           c.Config.TfvarsUpload,
+		ExtractWorkspaceLocals: c.Config.ExtractWorkspaceLocals,
+		WorkspaceTfvarsFiles:   c.Config.WorkspaceTfvarsFiles,
+		VarFiles:               c.Config.VarFiles,
+		SensitiveVariables:     sensitive,
+		BackendConfig:          c.Config.BackendConfig,
+		ConvertBackendConfig:   c.Config.ConvertBackendConfig,
+		ModulesDir:             c.Config.ModulesDir,
+		NoFormat:               c.Config.NoFormat,
+		Steps:                  steps,
+	}
+
+	// terraform is checked before it runs so that a binary that does not satisfy the module's requirements cannot
+	// upgrade its state
+	c.bin = c.Config.TerraformBin
+	var tfVersion *terraform.Version
+	if c.runsTerraform() {
+		bin, v, diags := checkTerraform(c.Config.TerraformBin, c.Config.DryRun)
+		c.diags(diags)
+		if diags.HasErrors() {
+			return 1
+		}
+
+		c.bin, tfVersion = bin, v
+		if tfVersion != nil {
+			c.Ui.Info(fmt.Sprintf("Using Terraform %s (%s)", tfVersion, c.bin))
+			if c.result != nil {
+				c.result.setTerraform(c.bin, tfVersion)
+			}
+		}
+	} else if bin, err := terraformBin(c.Config.TerraformBin); err == nil {
+		c.bin = bin
+	}
+
+	// the workspace variable is validated against the existing workspaces. In a dry run, the warning for workspaces
+	// that cannot be discovered is printed with the workspace plan.
+	discovered, err := discoverWorkspaces(c.terraform(abspath), c.Config.BackendConfig, c.listsWorkspaces())
+	if err == nil {
+		config.Workspaces = discovered.Names()
+	} else if !c.Config.DryRun {
+		c.diags(hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Workspaces not discovered",
+				Detail:   fmt.Sprintf("The module's workspaces could not be listed, so the workspace variable is not validated against them: %v", err),
 			},
-		},
-		WorkspaceVariable: c.Config.WorkspaceVariable,
-		TfvarsFilename:    c.Config.TfvarsFilename,
-		ModulesDir:        c.Config.ModulesDir,
-	})
+		})
+	}
+
+	if c.Config.Interactive {
+		steps, code := c.confirmSteps(path, config)
+		if code != 0 {
+			return code
+		}
+		if len(steps) == 0 {
+			c.Ui.Info("No steps selected.")
+			return 0
+		}
+		config.Steps = steps
+	}
+
+	migration, diags := migrate.New(path, config)
 
 	if diags.HasErrors() {
-		c.printDiags(diags)
+		c.diags(diags)
 		return 1
 	}
 
-	changes, diags := migration.Changes()
+	if tfVersion != nil {
+		diags = append(diags, terraform.CheckVersion(tfVersion, migration.TerraformRequirements())...)
+	}
+
+	changes, cDiags := migration.Changes()
+	diags = append(diags, cDiags...)
+
+	// values are read before the changes delete the tfvars files
+	variables, vDiags := migration.Variables()
+	diags = append(diags, vDiags...)
+
+	occurrences, oDiags := migration.WorkspaceOccurrences()
+	diags = append(diags, oDiags...)
+
+	c.diags(diags)
 	if diags.HasErrors() {
-		c.printDiags(diags)
 		return 1
 	}
 
+	c.variables(variables)
+	c.workspaceReplacements(occurrences)
+
+	if c.Config.DryRun {
+		// the backend may not be initialized, so workspaces are only listed if they can be discovered
+		if _, code := c.planWorkspaces(abspath, backend.Workspaces, true); code != 0 {
+			return code
+		}
+
+		if c.result != nil {
+			return c.changes(changes)
+		}
+		return c.printDiff(changes)
+	}
+
 	if !c.Config.NoInit {
 		c.Ui.Info("Running 'terraform init' prior to updating backend")
 		c.Ui.Info("This ensures that Terraform has persisted the existing backend configuration to local state")
-		fmt.Println()
+		c.Ui.Output("")
 
-		if code := c.terraformInit(abspath); code != 0 {
+		options := terraform.InitOptions{
+			BackendConfig: c.Config.BackendConfig,
+			NoInput:       c.Config.ForceCopy,
+		}
+		if _, code := c.terraformInit(initPhaseBefore, abspath, options); code != 0 {
 			return code
 		}
+	} else if c.result != nil {
+		c.result.addInit(initPhaseBefore, initStatusSkipped, 0, false)
 	}
 
-	if err := changes.WriteFiles(); err != nil {
-		c.Ui.Error(err.Error())
+	api := c.Config.Provision.Enabled || len(variables) != 0 || c.Config.CopyState
+
+	// workspaces are discovered after the existing backend is initialized so that they can be listed
+	pairs, code := c.planWorkspaces(abspath, backend.Workspaces, c.Config.NoInit && !api)
+	if code != 0 {
+		return code
+	}
+
+	if api {
+		client, err := c.api(backend)
+		if err != nil {
+			c.error(err)
+			return 1
+		}
+
+		if c.Config.Provision.Enabled {
+			c.Ui.Info("Provisioning Terraform Cloud workspaces")
+			if code := c.provision(client, pairs, abspath, backend); code != 0 {
+				return code
+			}
+		}
+
+		if len(variables) != 0 {
+			c.Ui.Info("Uploading variables")
+			if code := c.uploadVariables(client, pairs, backend.Organization, variables); code != 0 {
+				return code
+			}
+		}
+
+		// state is uploaded before files are written so that a failed upload leaves the module unchanged
+		if c.Config.CopyState {
+			c.Ui.Info("Copying state to Terraform Cloud")
+			states, code := c.pullStates(abspath, pairs)
+			if code != 0 {
+				return code
+			}
+			if code := c.uploadStates(client, backend.Organization, states); code != 0 {
+				return code
+			}
+		}
+	}
+
+	journal, err := configwrite.NewJournal(changes)
+	if err != nil {
+		c.error(fmt.Errorf("failed to record journal: %v", err))
+		return 1
+	}
+
+	if _, err := journal.Save(abspath); err != nil {
+		c.error(fmt.Errorf("failed to save journal: %v", err))
 		return 1
 	}
 
-	for path, change := range changes {
-		str := path
-		if change.Rename != "" {
-			str = fmt.Sprintf("%s -> %s", path, change.Destination(path))
+	// hashes are computed from the original files, so changes are recorded before they are written
+	if code := c.changes(changes); code != 0 {
+		return code
+	}
+
+	backendConfig := migration.BackendConfigFiles()
+
+	// the existing backend's state is read before its configuration is replaced, to check that init copies it
+	var source *terraform.StateSnapshot
+	if !c.Config.NoInit && !c.Config.CopyState {
+		source, err = c.sourceState(abspath)
+		if err != nil {
+			c.error(err)
+			return 1
 		}
+	}
 
-		fmt.Println(str)
+	if err := changes.WriteFiles(); err != nil {
+		c.error(err)
+		c.Ui.Error("Run 'terraform-cloud-migrate rollback' to restore the previous configuration.")
+		return 1
 	}
 
-	if !c.Config.NoInit {
+	if !c.Config.NoInit && c.Config.CopyState {
+		c.Ui.Info("Running 'terraform init' to configure the new backend")
+		c.Ui.Output("")
+
+		// state was already uploaded, so the previous backend is ignored rather than migrated
+		options := terraform.InitOptions{
+			BackendConfig: backendConfig,
+			Reconfigure:   true,
+			NoInput:       true,
+			Workspace:     newWorkspace(abspath, pairs, backend.Workspaces),
+		}
+		if _, code := c.terraformInit(initPhaseAfter, abspath, options); code != 0 {
+			return code
+		}
+	} else if !c.Config.NoInit {
 		c.Ui.Info("Running 'terraform init' to copy state")
-		c.Ui.Info("When prompted, type 'yes' to confirm")
-		fmt.Println()
 
-		if code := c.terraformInit(abspath); code != 0 {
+		options := terraform.InitOptions{BackendConfig: backendConfig, Source: source}
+		if c.Config.ForceCopy {
+			options.ForceCopy = true
+			options.NoInput = true
+			options.Workspace = newWorkspace(abspath, pairs, backend.Workspaces)
+		} else {
+			c.Ui.Info("When prompted, type 'yes' to confirm")
+		}
+		c.Ui.Output("")
+
+		result, code := c.terraformInit(initPhaseAfter, abspath, options)
+		if code != 0 {
 			return code
 		}
+		if source != nil && !result.StateMigrated {
+			c.diags(hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "State not copied",
+					Detail:   "'terraform init' succeeded but the new backend does not have the current workspace's state. Check that the Terraform Cloud workspaces have the expected state.",
+				},
+			})
+		}
+	} else if c.result != nil {
+		c.result.addInit(initPhaseAfter, initStatusSkipped, 0, false)
 	}
 
 	c.Ui.Info("Migration complete!")
@@ -151,6 +492,306 @@ func (c *RunCommand) Run(args []string) int {
 	return 0
 }
 
+// selectSteps returns the identifiers of the steps to run given --only-step and --skip-step
+func selectSteps(only, skip []string) ([]string, error) {
+	for _, id := range append(append([]string{}, only...), skip...) {
+		if !contains(migrate.StepIDs, id) {
+			return nil, fmt.Errorf("unknown step '%s', must be one of: %s", id, strings.Join(migrate.StepIDs, ", "))
+		}
+	}
+
+	steps := make([]string, 0, len(migrate.StepIDs))
+	for _, id := range migrate.StepIDs {
+		if len(only) != 0 && !contains(only, id) {
+			continue
+		}
+		if contains(skip, id) {
+			continue
+		}
+		steps = append(steps, id)
+	}
+
+	if len(steps) == 0 {
+		return nil, errors.New("no steps selected")
+	}
+
+	return steps, nil
+}
+
+// confirmSteps shows the changes proposed by each step on top of the steps accepted before it and asks whether to apply it
+func (c *RunCommand) confirmSteps(path string, config migrate.Config) ([]string, int) {
+	accepted := make([]string, 0, len(config.Steps))
+
+	for _, id := range config.Steps {
+		single := config
+		single.Steps = []string{id}
+
+		migration, diags := migrate.New(path, single)
+		if diags.HasErrors() {
+			c.diags(diags)
+			return nil, 1
+		}
+
+		// the preview runs the accepted steps first so that each diff matches the one the migration will apply
+		preview := config
+		preview.Steps = append(append([]string{}, accepted...), id)
+
+		previewMigration, diags := migrate.New(path, preview)
+		if diags.HasErrors() {
+			c.diags(diags)
+			return nil, 1
+		}
+
+		changes, diags := previewMigration.Steps().Changes()
+		if diags.HasErrors() {
+			c.diags(diags)
+			return nil, 1
+		}
+
+		for _, step := range migration.Steps() {
+			c.Ui.Output("")
+			c.Ui.Output(fmt.Sprintf("Step: %s (%s)", step.Name(), id))
+			c.Ui.Info(step.Description())
+
+			diff := changes.StepDiff(step.Name())
+			if diff == "" {
+				c.Ui.Info("No changes.")
+				continue
+			}
+
+			c.Ui.Output(strings.TrimSuffix(diff, "\n"))
+
+			answer, err := c.Ui.Ask("Apply this step? Only 'yes' will be accepted:")
+			if err != nil {
+				c.error(err)
+				return nil, 1
+			}
+
+			if answer == "yes" && !contains(accepted, id) {
+				accepted = append(accepted, id)
+			}
+		}
+	}
+
+	return accepted, 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// error reports an error that is not tied to configuration as a diagnostic
+func (c *RunCommand) error(err error) {
+	c.diags(hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  err.Error(),
+		},
+	})
+}
+
+func (c *RunCommand) diags(diags hcl.Diagnostics) {
+	if c.result != nil {
+		c.result.addDiags(diags)
+		return
+	}
+
+	printDiags(c.Ui, diags)
+}
+
+// variables lists the variables that will be uploaded, without their values
+func (c *RunCommand) variables(variables []configwrite.Variable) {
+	if c.result != nil {
+		c.result.addVariables(variables)
+		return
+	}
+
+	if len(variables) == 0 {
+		return
+	}
+
+	c.Ui.Info("Variables to upload:")
+	for _, variable := range variables {
+		c.Ui.Info("  " + variableSummary(variable))
+	}
+}
+
+// runsTerraform returns whether terraform runs to initialize the module or pull its state
+func (c *RunCommand) runsTerraform() bool {
+	return !c.Config.NoInit || (c.Config.CopyState && c.Config.StateSource == stateSourcePull)
+}
+
+// listsWorkspaces returns whether terraform may run to list the module's workspaces
+func (c *RunCommand) listsWorkspaces() bool {
+	return !c.Config.DryRun && c.runsTerraform()
+}
+
+// newWorkspace returns the workspace to select with TF_WORKSPACE when 'terraform init' runs without input after the
+// backend is changed. It is the current workspace's Terraform Cloud workspace, or the first if the current workspace
+// is not migrated, named without the prefix as the remote backend names it. A single named workspace needs no selection.
+func newWorkspace(abspath string, pairs []workspacePair, workspaces migrate.WorkspaceConfig) string {
+	if workspaces.Name != "" || len(pairs) == 0 {
+		return ""
+	}
+
+	current, _ := state.CurrentWorkspace(abspath)
+	remote := pairs[0].Remote
+	for _, pair := range pairs {
+		if pair.Local == current {
+			remote = pair.Remote
+		}
+	}
+
+	return strings.TrimPrefix(remote, workspaces.Prefix)
+}
+
+// planWorkspaces discovers the module's workspaces, maps them to Terraform Cloud workspaces, and lists the mapping.
+// If optional is set, workspaces that cannot be discovered are a warning instead of an error, and terraform only
+// lists them if it is allowed to run.
+func (c *RunCommand) planWorkspaces(abspath string, workspaces migrate.WorkspaceConfig, optional bool) ([]workspacePair, int) {
+	discovered, err := discoverWorkspaces(c.terraform(abspath), c.Config.BackendConfig, !optional || c.listsWorkspaces())
+	if err != nil && optional {
+		c.diags(hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Workspaces not discovered",
+				Detail:   fmt.Sprintf("The module's workspaces could not be listed, so they were not checked and the workspace variable is not validated against them: %v", err),
+			},
+		})
+		return nil, 0
+	}
+	if err != nil {
+		c.error(fmt.Errorf("failed to discover workspaces: %v", err))
+		return nil, 1
+	}
+
+	pairs, diags := workspacePairs(discovered, workspaces)
+	c.diags(diags)
+	if diags.HasErrors() {
+		return nil, 1
+	}
+
+	c.workspaceTable(discovered, pairs)
+	return pairs, 0
+}
+
+// workspaceTable lists each existing workspace and the Terraform Cloud workspace it is migrated to
+func (c *RunCommand) workspaceTable(discovered *state.Workspaces, pairs []workspacePair) {
+	remotes := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		remotes[pair.Local] = pair.Remote
+	}
+
+	if c.result != nil {
+		c.result.addWorkspacePlan(discovered, remotes)
+		return
+	}
+
+	width := 0
+	for _, workspace := range discovered.All {
+		if len(workspace.Name) > width {
+			width = len(workspace.Name)
+		}
+	}
+
+	c.Ui.Info("Workspaces:")
+	for _, workspace := range discovered.All {
+		remote, ok := remotes[workspace.Name]
+		if !ok {
+			remote = "(not migrated)"
+		}
+		c.Ui.Info(fmt.Sprintf("  %-*s -> %s", width, workspace.Name, remote))
+	}
+}
+
+// workspaceReplacements lists each terraform.workspace reference that is replaced with the workspace variable
+func (c *RunCommand) workspaceReplacements(ranges []hcl.Range) {
+	if c.result != nil {
+		c.result.addWorkspaceReplacements(ranges)
+		return
+	}
+
+	if len(ranges) == 0 {
+		return
+	}
+
+	c.Ui.Info(fmt.Sprintf("Replacing terraform.workspace with var.%s:", c.Config.WorkspaceVariable))
+	for _, rng := range ranges {
+		c.Ui.Info(fmt.Sprintf("  %s:%d", rng.Filename, rng.Start.Line))
+	}
+}
+
+func (c *RunCommand) changes(changes configwrite.Changes) int {
+	if c.result != nil {
+		if err := c.result.addChanges(changes); err != nil {
+			c.error(fmt.Errorf("failed to hash changes: %v", err))
+			return 1
+		}
+		return 0
+	}
+
+	for _, path := range changes.Paths() {
+		c.Ui.Output(changeSummary(path, changes[path]))
+	}
+
+	return 0
+}
+
+// changeSummary describes a change with its destination and the steps that produced it
+func changeSummary(path string, change *configwrite.Change) string {
+	str := path
+	if change.Delete {
+		str = fmt.Sprintf("%s (deleted)", path)
+	} else if change.Rename != "" {
+		str = fmt.Sprintf("%s -> %s", path, change.Destination(path))
+	}
+
+	if len(change.Steps) == 0 {
+		return str
+	}
+
+	return fmt.Sprintf("%s: %s", str, strings.Join(change.StepNames(), ", "))
+}
+
+// sourceState returns the state of the current workspace in the module's existing backend, or nil if it is empty
+func (c *RunCommand) sourceState(abspath string) (*terraform.StateSnapshot, error) {
+	current, err := state.CurrentWorkspace(abspath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the current workspace: %v", err)
+	}
+
+	b, err := c.terraform(abspath).StatePull(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state of workspace '%s': %v", current, err)
+	}
+
+	return terraform.ParseStateSnapshot(b)
+}
+
+func (c *RunCommand) terraformInit(phase, path string, options terraform.InitOptions) (*terraform.InitResult, int) {
+	var stdout io.Writer = os.Stdout
+	if c.result != nil {
+		stdout = os.Stderr
+	}
+
+	result, code := terraformInit(c.Ui, c.bin, path, stdout, options)
+
+	if c.result != nil {
+		status := initStatusSucceeded
+		if code != 0 {
+			status = initStatusFailed
+		}
+		c.result.addInit(phase, status, code, result != nil && result.StateMigrated)
+	}
+
+	return result, code
+}
+
 func (c *RunCommand) Help() string {
 	return strings.TrimSpace(`
 Usage: terraform-cloud-migrate run [DIR] [options]
@@ -164,35 +805,93 @@ func (c *RunCommand) Synopsis() string {
 	return "Run Terraform Cloud migration"
 }
 
-func (c *RunCommand) printDiags(diags hcl.Diagnostics) {
+func validateBackend(config migrate.RemoteBackendConfig) error {
+	if config.Style != configwrite.BackendStyleBackend && config.Style != configwrite.BackendStyleCloud {
+		return fmt.Errorf("backend style must be '%s' or '%s'", configwrite.BackendStyleBackend, configwrite.BackendStyleCloud)
+	}
+
+	workspaceOptions := 0
+	for _, set := range []bool{config.Workspaces.Name != "", config.Workspaces.Prefix != "", len(config.Workspaces.Tags) != 0} {
+		if set {
+			workspaceOptions++
+		}
+	}
+
+	if workspaceOptions == 0 {
+		return errors.New("workspace name, prefix, or tags are required")
+	}
+
+	if workspaceOptions > 1 {
+		return errors.New("workspace can only have one of a name, prefix, or tags")
+	}
+
+	if len(config.Workspaces.Map) != 0 && config.Workspaces.Name != "" {
+		return errors.New("workspace map requires a workspace prefix or tags")
+	}
+
+	for local, remote := range config.Workspaces.Map {
+		if !strings.HasPrefix(remote, config.Workspaces.Prefix) {
+			return fmt.Errorf("workspace '%s' is mapped to '%s', which does not start with the prefix '%s'", local, remote, config.Workspaces.Prefix)
+		}
+	}
+
+	return nil
+}
+
+func printDiags(ui cli.Ui, diags hcl.Diagnostics) {
 	for _, diag := range diags {
 		switch diag.Severity {
 		case hcl.DiagError:
-			c.Ui.Error(diag.Summary)
+			ui.Error(diag.Summary)
 		case hcl.DiagWarning:
-			c.Ui.Warn(diag.Summary)
+			ui.Warn(diag.Summary)
+		}
+		ui.Info(diag.Detail)
+		if diag.Subject != nil {
+			ui.Info(diag.Subject.String())
 		}
-		c.Ui.Info(diag.Detail)
-		c.Ui.Info(diag.Subject.String())
 	}
 }
 
-func (c *RunCommand) terraformInit(path string) int {
-	cmd := exec.Command("terraform", "init", path)
+func (c *RunCommand) printDiff(changes configwrite.Changes) int {
+	if len(changes) == 0 {
+		c.Ui.Info("No changes. The module is already configured for Terraform Cloud.")
+		return 0
+	}
 
-	cmd.Dir = path
+	return printDiff(c.Ui, changes)
+}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func printDiff(ui cli.Ui, changes configwrite.Changes) int {
+	diff, err := changes.Diff()
+	if err != nil {
+		ui.Error(fmt.Sprintf("failed to diff changes: %v", err))
+		return 1
+	}
 
-	if err := cmd.Run(); err != nil {
-		if err, ok := err.(*exec.ExitError); ok {
-			return err.ExitCode()
-		}
+	ui.Output(strings.TrimSuffix(diff, "\n"))
+	return 0
+}
 
-		c.Ui.Error(fmt.Sprintf("failed to terraform init: %v", err))
+// terraformInit runs 'terraform init' in a module with its output written to stdout and stderr as well as parsed.
+// It returns the result, or nil if terraform could not be run, and the exit code.
+func terraformInit(ui cli.Ui, bin, path string, stdout io.Writer, options terraform.InitOptions) (*terraform.InitResult, int) {
+	runner := &terraform.Runner{
+		Bin:    bin,
+		Dir:    path,
+		Stdin:  os.Stdin,
+		Stdout: stdout,
+		Stderr: os.Stderr,
 	}
 
-	return 0
+	result, err := runner.Init(options)
+	if result == nil {
+		ui.Error(fmt.Sprintf("failed to terraform init: %v", err))
+		return nil, 1
+	}
+	if err != nil && result.ExitCode == 0 {
+		ui.Warn(err.Error())
+	}
+
+	return result, result.ExitCode
 }
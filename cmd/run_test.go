@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureStdout returns what f writes to stdout
+func captureStdout(t *testing.T, f func()) []byte {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRunCommand_jsonConfigFile(t *testing.T) {
+	for name, config := range map[string]string{
+		"flag":        `organization = "org"`,
+		"config file": "organization = \"org\"\njson = true",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{
+				ConfigFilename: config,
+				"main.tf":      "terraform {\n  backend \"local\" {}\n}\n",
+			})
+			defer os.RemoveAll(dir)
+
+			args := []string{"--workspace-name", "app", "--dry-run", "--no-init", filepath.Join(dir)}
+			if name == "flag" {
+				args = append([]string{"--json"}, args...)
+			}
+
+			ui := cli.NewMockUi()
+			var code int
+			stdout := captureStdout(t, func() {
+				code = NewRunCommand(ui).Run(args)
+			})
+
+			assert.Equal(t, 0, code)
+			assert.Empty(t, ui.OutputWriter.String(), "no output is written to the UI's stdout")
+
+			var result jsonResult
+			if assert.NoError(t, json.Unmarshal(stdout, &result), string(stdout)) {
+				assert.True(t, result.Success)
+				assert.True(t, result.DryRun)
+			}
+		})
+	}
+}
+
+func TestRunCommand_jsonConfigFileError(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		ConfigFilename: "unknown = true",
+		"main.tf":      "",
+	})
+	defer os.RemoveAll(dir)
+
+	var code int
+	stdout := captureStdout(t, func() {
+		code = NewRunCommand(cli.NewMockUi()).Run([]string{"--json", dir})
+	})
+
+	assert.Equal(t, 1, code)
+
+	var result jsonResult
+	if assert.NoError(t, json.Unmarshal(stdout, &result), string(stdout)) {
+		assert.False(t, result.Success)
+		if assert.Len(t, result.Diagnostics, 1) {
+			assert.Equal(t, "Unsupported argument", result.Diagnostics[0].Summary)
+		}
+	}
+}
+
+func TestRunCommand_convertBackendConfigCloud(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf":          "terraform {\n  backend \"s3\" {}\n}\n",
+		"prod.backend.hcl": `bucket = "terraform-state"`,
+	})
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	code := NewRunCommand(ui).Run([]string{
+		"--backend-style", "cloud", "--convert-backend-config",
+		"--organization", "org", "--workspace-name", "app",
+		"--dry-run", "--no-init", dir,
+	})
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, ui.ErrorWriter.String(), "--convert-backend-config requires --backend-style backend")
+}
+
+func TestPlanCommand_workspaceMap(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf": "terraform {\n  backend \"local\" {}\n}\n",
+		"terraform.tfstate.d/stg/terraform.tfstate": `{"version": 4}`,
+	})
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	var code int
+	captureStdout(t, func() {
+		code = NewPlanCommand(ui).Run([]string{
+			"--organization", "org", "--workspace-prefix", "app-",
+			"--workspace-map", "stg=app-staging", "--no-init", dir,
+		})
+	})
+
+	assert.Equal(t, 0, code, ui.ErrorWriter.String())
+	assert.NotContains(t, ui.ErrorWriter.String(), "--copy-state")
+}
+
+func TestPlanCommand_workspacesNotDiscovered(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf": "terraform {\n  backend \"s3\" {}\n}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	var code int
+	captureStdout(t, func() {
+		code = NewPlanCommand(ui).Run([]string{"--organization", "org", "--workspace-prefix", "app-", "--no-init", dir})
+	})
+
+	assert.Equal(t, 0, code, ui.ErrorWriter.String())
+	assert.Contains(t, ui.ErrorWriter.String(), "Workspaces not discovered")
+	assert.Contains(t, ui.OutputWriter.String(), "the workspace variable is not validated")
+}
+
+func TestRunCommand_interactive(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		header string
+	}{
+		{
+			name:   "accepted",
+			input:  "yes\n",
+			header: "@@ -10,5 +10,5 @@",
+		},
+		{
+			name:   "declined",
+			input:  "no\n",
+			header: "@@ -5,5 +5,5 @@",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{
+				"main.tf": "terraform {\n  backend \"s3\" {\n    bucket = \"b\"\n  }\n}\n\nlocals {\n  env = terraform.workspace\n}\n",
+			})
+			defer os.RemoveAll(dir)
+
+			ui := cli.NewMockUi()
+			ui.InputReader = strings.NewReader(tc.input + "no\n")
+			var code int
+			captureStdout(t, func() {
+				code = NewRunCommand(ui).Run([]string{
+					"--interactive", "--dry-run", "--no-init",
+					"--organization", "org", "--workspace-name", "app",
+					"--only-step", "remote-backend,terraform-workspace", dir,
+				})
+			})
+
+			assert.Equal(t, 0, code, ui.ErrorWriter.String())
+			// the backend block grows by five lines, so the terraform.workspace replacement moves when it is accepted
+			assert.Contains(t, ui.OutputWriter.String(), tc.header, "the preview of a step includes the steps accepted before it")
+		})
+	}
+}
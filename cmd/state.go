@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/bendrucker/terraform-cloud-migrate/tfe"
+)
+
+// State sources for --state-source
+const (
+	stateSourcePull  = "pull"
+	stateSourceLocal = "local"
+)
+
+// pulledState is the state of one of the module's workspaces, read before its backend is changed
+type pulledState struct {
+	workspacePair
+	State []byte
+}
+
+func validateStateSource(name string) error {
+	if name != stateSourcePull && name != stateSourceLocal {
+		return fmt.Errorf("state source must be '%s' or '%s'", stateSourcePull, stateSourceLocal)
+	}
+	return nil
+}
+
+// newStateSource returns the named state source for the module in the runner's directory. The local source reads the
+// files of the module's local backend, configured with its "path" and "workspace_dir" and backendConfig.
+func newStateSource(name string, runner *terraform.Runner, backendConfig []string) (state.Source, error) {
+	if err := validateStateSource(name); err != nil {
+		return nil, err
+	}
+
+	if name == stateSourcePull {
+		return &state.Pull{Terraform: runner}, nil
+	}
+
+	writer, diags := moduleWriter(runner.Dir, backendConfig)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	local := writer.LocalBackend()
+	if local == nil {
+		return nil, fmt.Errorf("state source '%s' requires the local backend, but the module uses the '%s' backend", stateSourceLocal, writer.Backend().Type)
+	}
+	return local, nil
+}
+
+// validateCopyState checks the options for copying state. The workspace map requires copying state since
+// 'terraform init' names each workspace with the prefix, except in a dry run, which does not copy state.
+func validateCopyState(config *RunCommandConfig, workspaces migrate.WorkspaceConfig) error {
+	if !config.CopyState {
+		if len(workspaces.Map) != 0 && !config.DryRun {
+			return errors.New("workspace map requires --copy-state, since 'terraform init' copies each workspace to its name with the prefix")
+		}
+		return nil
+	}
+
+	if config.Organization == "" {
+		return errors.New("--organization is required to copy state")
+	}
+
+	return validateStateSource(config.StateSource)
+}
+
+// pullStates reads the state of every workspace from the existing backend. Workspaces without state are skipped.
+func (c *RunCommand) pullStates(abspath string, pairs []workspacePair) ([]pulledState, int) {
+	source, err := newStateSource(c.Config.StateSource, c.terraform(abspath), c.Config.BackendConfig)
+	if err != nil {
+		c.error(err)
+		return nil, 1
+	}
+
+	states := make([]pulledState, 0, len(pairs))
+	for _, pair := range pairs {
+		b, err := source.State(pair.Local)
+		if err != nil {
+			c.error(err)
+			return nil, 1
+		}
+
+		if b == nil {
+			c.Ui.Info(fmt.Sprintf("Workspace '%s' has no state", pair.Local))
+			continue
+		}
+
+		states = append(states, pulledState{workspacePair: pair, State: b})
+	}
+
+	return states, 0
+}
+
+// uploadStates creates a state version in each Terraform Cloud workspace from the pulled state
+func (c *RunCommand) uploadStates(client *tfe.Client, organization string, states []pulledState) int {
+	for _, s := range states {
+		ws, err := client.Workspace(organization, s.Remote)
+		if err == tfe.ErrNotFound {
+			c.error(fmt.Errorf("workspace %s/%s does not exist, use --provision to create it", organization, s.Remote))
+			return 1
+		}
+		if err != nil {
+			c.error(fmt.Errorf("failed to read workspace '%s': %v", s.Remote, err))
+			return 1
+		}
+
+		sv, created, err := client.UploadState(ws.ID, s.State)
+		if err != nil {
+			c.error(fmt.Errorf("failed to upload state from workspace '%s' to '%s': %v", s.Local, s.Remote, err))
+			return 1
+		}
+
+		if created {
+			c.Ui.Info(fmt.Sprintf("Uploaded state from workspace '%s' to %s/%s (serial %d)", s.Local, organization, s.Remote, sv.Serial))
+		} else {
+			c.Ui.Info(fmt.Sprintf("State from workspace '%s' was already uploaded to %s/%s (serial %d)", s.Local, organization, s.Remote, sv.Serial))
+		}
+
+		if c.result != nil {
+			c.result.addState(s.workspacePair, sv, created)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// terraformBin finds the terraform binary, which is terraform on PATH unless bin is set, and returns its absolute path
+// so that it does not depend on the module directory that terraform runs in
+func terraformBin(bin string) (string, error) {
+	if bin == "" {
+		bin = "terraform"
+	}
+
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(path)
+}
+
+// checkTerraform finds the terraform binary and detects its version. It returns the binary to run, which is bin
+// unchanged if it was not found. If optional is set, a binary that cannot be run is a warning instead of an error.
+func checkTerraform(bin string, optional bool) (string, *terraform.Version, hcl.Diagnostics) {
+	path, err := terraformBin(bin)
+	if err == nil {
+		var v *terraform.Version
+		if v, err = (&terraform.Runner{Bin: path}).Version(); err == nil {
+			return path, v, nil
+		}
+	} else {
+		path = bin
+	}
+
+	severity := hcl.DiagError
+	if optional {
+		severity = hcl.DiagWarning
+	}
+
+	return path, nil, hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: severity,
+			Summary:  "Terraform version not detected",
+			Detail:   fmt.Sprintf("The version of terraform could not be checked against the module's requirements: %v. Use --terraform-bin to select a terraform binary.", err),
+		},
+	}
+}
+
+// terraform returns a runner for the module in dir that uses the selected terraform binary
+func (c *RunCommand) terraform(dir string) *terraform.Runner {
+	return &terraform.Runner{Bin: c.bin, Dir: dir}
+}
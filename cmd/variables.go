@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/tfe"
+)
+
+// uploadVariables creates or updates a workspace variable for each tfvars value in every workspace. Values from
+// workspace-keyed locals are uploaded only to the workspace they were set for.
+func (c *RunCommand) uploadVariables(client *tfe.Client, pairs []workspacePair, organization string, variables []configwrite.Variable) int {
+	for _, pair := range pairs {
+		name := pair.Remote
+		ws, err := client.Workspace(organization, name)
+		if err == tfe.ErrNotFound {
+			c.error(fmt.Errorf("workspace %s/%s does not exist, use --provision to create it", organization, name))
+			return 1
+		}
+		if err != nil {
+			c.error(fmt.Errorf("failed to read workspace '%s': %v", name, err))
+			return 1
+		}
+
+		existing, err := client.Variables(ws.ID)
+		if err != nil {
+			c.error(fmt.Errorf("failed to list variables of workspace '%s': %v", name, err))
+			return 1
+		}
+
+		uploaded := 0
+		for _, variable := range variables {
+			if variable.Workspace != "" && variable.Workspace != pair.Local {
+				continue
+			}
+
+			v, created, err := client.UpsertVariable(ws.ID, existing, tfe.VariableOptions{
+				Key:       variable.Key,
+				Value:     variable.Value,
+				Category:  tfe.CategoryTerraform,
+				HCL:       variable.HCL,
+				Sensitive: variable.Sensitive,
+			})
+			if err != nil {
+				c.error(fmt.Errorf("failed to upload variable '%s' to workspace '%s': %v", variable.Key, name, err))
+				return 1
+			}
+			if created {
+				existing = append(existing, v)
+			}
+			uploaded++
+		}
+
+		c.Ui.Info(fmt.Sprintf("Uploaded %d variables to workspace %s/%s", uploaded, organization, name))
+	}
+
+	return 0
+}
+
+func validateTfvarsUpload(config *RunCommandConfig) error {
+	if config.WorkspaceTfvarsFiles && !config.ExtractWorkspaceLocals {
+		return errors.New("--workspace-tfvars-files requires --extract-workspace-locals")
+	}
+	if !config.TfvarsUpload && len(config.VarFiles) != 0 {
+		return errors.New("--var-file requires --tfvars-upload")
+	}
+
+	// extracted locals are uploaded unless they are written to files
+	upload := config.TfvarsUpload || (config.ExtractWorkspaceLocals && !config.WorkspaceTfvarsFiles)
+	if !upload {
+		return nil
+	}
+
+	if config.Organization == "" {
+		return errors.New("--organization is required to upload variables")
+	}
+
+	return nil
+}
+
+// variableSummary describes a variable without its value
+func variableSummary(variable configwrite.Variable) string {
+	str := fmt.Sprintf("%s (from %s)", variable.Key, variable.Filename)
+	if variable.HCL {
+		str += ", hcl"
+	}
+	if variable.Sensitive {
+		str += ", sensitive"
+	}
+	if variable.Workspace != "" {
+		str += ", workspace " + variable.Workspace
+	}
+	return str
+}
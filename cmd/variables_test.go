@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTfvarsUpload(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RunCommandConfig
+		err    string
+	}{
+		{
+			name:   "none",
+			config: RunCommandConfig{},
+		},
+		{
+			name:   "tfvars upload",
+			config: RunCommandConfig{TfvarsUpload: true, Organization: "org"},
+		},
+		{
+			name:   "tfvars upload without organization",
+			config: RunCommandConfig{TfvarsUpload: true},
+			err:    "--organization is required to upload variables",
+		},
+		{
+			name:   "var file",
+			config: RunCommandConfig{VarFiles: []string{"prod.tfvars"}},
+			err:    "--var-file requires --tfvars-upload",
+		},
+		{
+			name:   "workspace locals",
+			config: RunCommandConfig{ExtractWorkspaceLocals: true},
+			err:    "--organization is required to upload variables",
+		},
+		{
+			name:   "workspace locals files",
+			config: RunCommandConfig{ExtractWorkspaceLocals: true, WorkspaceTfvarsFiles: true},
+		},
+		{
+			name:   "files without workspace locals",
+			config: RunCommandConfig{WorkspaceTfvarsFiles: true},
+			err:    "--workspace-tfvars-files requires --extract-workspace-locals",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTfvarsUpload(&tc.config)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
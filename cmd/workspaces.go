@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// workspacePair maps a workspace of the module's existing backend to a Terraform Cloud workspace
+type workspacePair struct {
+	Local  string
+	Remote string
+}
+
+// moduleWriter loads the module in dir with partial backend configuration, as passed to --backend-config
+func moduleWriter(dir string, backendConfig []string) (*configwrite.Writer, hcl.Diagnostics) {
+	writer, diags := configwrite.New(dir)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	diags = append(diags, writer.SetBackendConfig(backendConfig)...)
+	return writer, diags
+}
+
+// discoverWorkspaces finds the existing workspaces of the module in the runner's directory. Workspaces of the local
+// backend are read from its "path" and "workspace_dir", which may be set by backendConfig. Workspaces of other
+// backends are listed with 'terraform workspace list', which requires the backend to be initialized, and cannot be
+// discovered unless list is set.
+func discoverWorkspaces(runner *terraform.Runner, backendConfig []string, list bool) (*state.Workspaces, error) {
+	writer, diags := moduleWriter(runner.Dir, backendConfig)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	local := writer.LocalBackend()
+	if local != nil {
+		return state.Discover(runner.Dir, local, nil)
+	}
+	if !list {
+		return nil, fmt.Errorf("workspaces of the '%s' backend can only be listed by running 'terraform workspace list'", writer.Backend().Type)
+	}
+
+	return state.Discover(runner.Dir, nil, func() ([]string, error) {
+		workspaces, err := runner.WorkspaceList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspaces: %v", err)
+		}
+		return workspaces, nil
+	})
+}
+
+// workspacePairs maps the module's workspaces to Terraform Cloud workspaces. With a name, the current workspace
+// maps to it. With a prefix or tags, each workspace maps to its name in the workspace map or to its name with the
+// prefix. The "default" workspace is only included if it is in the workspace map. Workspaces with state that
+// cannot be migrated are reported as errors, and workspaces that may have state as warnings.
+func workspacePairs(discovered *state.Workspaces, workspaces migrate.WorkspaceConfig) ([]workspacePair, hcl.Diagnostics) {
+	if workspaces.Name != "" {
+		return []workspacePair{{Local: discovered.Current, Remote: workspaces.Name}}, singleWorkspaceDiags(discovered, workspaces.Name)
+	}
+
+	var diags hcl.Diagnostics
+	pairs := make([]workspacePair, 0, len(discovered.All))
+	found := make(map[string]bool, len(discovered.All))
+	remotes := make(map[string]string, len(discovered.All))
+
+	for _, workspace := range discovered.All {
+		name := workspace.Name
+		found[name] = true
+
+		if _, mapped := workspaces.Map[name]; !mapped && name == state.DefaultWorkspace {
+			if !workspace.Empty {
+				diags = append(diags, workspaceNotMigrated(name, `Add it to the workspace map to migrate it.`))
+			}
+			continue
+		}
+
+		remote := workspaces.RemoteName(name)
+		if other, ok := remotes[remote]; ok {
+			diags = append(diags, workspaceError(fmt.Sprintf("Workspaces '%s' and '%s' both map to '%s'", other, name, remote)))
+			continue
+		}
+		remotes[remote] = name
+
+		pairs = append(pairs, workspacePair{Local: name, Remote: remote})
+	}
+
+	for name := range workspaces.Map {
+		if !found[name] {
+			diags = append(diags, workspaceError(fmt.Sprintf("Workspace '%s' in the workspace map does not exist", name)))
+		}
+	}
+
+	if len(pairs) == 0 {
+		diags = append(diags, workspaceError(fmt.Sprintf("No workspaces other than '%s' found", state.DefaultWorkspace)))
+	}
+
+	return pairs, diags
+}
+
+// singleWorkspaceDiags reports the workspaces other than the current workspace, which are not migrated to a single
+// named workspace. Workspaces with state are errors. The default workspace is a warning because every backend has it.
+func singleWorkspaceDiags(discovered *state.Workspaces, name string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var others []string
+
+	for _, workspace := range discovered.All {
+		switch {
+		case workspace.Name == discovered.Current || workspace.Empty:
+			continue
+		case workspace.Name == state.DefaultWorkspace:
+			diags = append(diags, workspaceNotMigrated(workspace.Name, fmt.Sprintf(`Only the current workspace, "%s", is migrated to "%s".`, discovered.Current, name)))
+		default:
+			others = append(others, workspace.Name)
+		}
+	}
+
+	if len(others) != 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Workspaces not migrated",
+			Detail:   fmt.Sprintf(`Only the current workspace, "%s", is migrated to "%s", but the module also has the workspaces %s. Use a workspace prefix or tags to migrate every workspace, or delete the other workspaces.`, discovered.Current, name, strings.Join(others, ", ")),
+		})
+	}
+
+	return diags
+}
+
+func workspaceNotMigrated(name, detail string) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Workspace not migrated",
+		Detail:   fmt.Sprintf(`The "%s" workspace may have state but is not migrated to Terraform Cloud. %s`, name, detail),
+	}
+}
+
+func workspaceError(summary string) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  summary,
+	}
+}
+
+// mergeWorkspaceMap reads a workspace map file, if set, and overrides its entries with those from flags
+func mergeWorkspaceMap(path string, flags map[string]string) (map[string]string, hcl.Diagnostics) {
+	out := make(map[string]string)
+
+	var diags hcl.Diagnostics
+	if path != "" {
+		var file map[string]string
+		file, diags = readWorkspaceMap(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for local, remote := range file {
+			out[local] = remote
+		}
+	}
+
+	for local, remote := range flags {
+		out[local] = remote
+	}
+
+	return out, diags
+}
+
+// readWorkspaceMap reads a file of attributes where each name is an existing workspace and each value is a Terraform Cloud workspace name
+func readWorkspaceMap(path string) (map[string]string, hcl.Diagnostics) {
+	parser := hclparse.NewParser()
+
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(path, ".json") {
+		file, diags = parser.ParseJSONFile(path)
+	} else {
+		file, diags = parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attrs, diags := file.Body.JustAttributes()
+	out := make(map[string]string, len(attrs))
+
+	for name, attr := range attrs {
+		value, vDiags := attr.Expr.Value(nil)
+		diags = append(diags, vDiags...)
+		if vDiags.HasErrors() {
+			continue
+		}
+
+		if value.IsNull() || value.Type() != cty.String {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid workspace name",
+				Detail:   fmt.Sprintf(`The Terraform Cloud workspace for "%s" must be a string.`, name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+
+		out[name] = value.AsString()
+	}
+
+	return out, diags
+}
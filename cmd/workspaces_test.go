@@ -0,0 +1,275 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	migrate "github.com/bendrucker/terraform-cloud-migrate"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverWorkspaces(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf": "terraform {\n  backend \"local\" {}\n}\n",
+		"terraform.tfstate.d/stg/terraform.tfstate": `{"version": 4}`,
+	})
+	defer os.RemoveAll(dir)
+
+	discovered, err := discoverWorkspaces(terraform.New(dir), nil, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"default", "stg"}, discovered.Names())
+	}
+
+	dir = testTempDir(t, map[string]string{
+		"main.tf": "terraform {\n  backend \"s3\" {}\n}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	_, err = discoverWorkspaces(terraform.New(dir), nil, false)
+	assert.EqualError(t, err, "workspaces of the 's3' backend can only be listed by running 'terraform workspace list'")
+}
+
+func TestWorkspacePairs(t *testing.T) {
+	tests := []struct {
+		name       string
+		discovered state.Workspaces
+		workspaces migrate.WorkspaceConfig
+		expected   []workspacePair
+		diags      []string
+	}{
+		{
+			name: "name",
+			discovered: state.Workspaces{
+				Current: "stg",
+				All:     []state.Workspace{{Name: "default", Empty: true}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{Name: "app"},
+			expected:   []workspacePair{{Local: "stg", Remote: "app"}},
+		},
+		{
+			name: "name with other workspaces",
+			discovered: state.Workspaces{
+				Current: "stg",
+				All:     []state.Workspace{{Name: "default"}, {Name: "prod"}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{Name: "app"},
+			expected:   []workspacePair{{Local: "stg", Remote: "app"}},
+			diags:      []string{"Workspace not migrated", "Workspaces not migrated"},
+		},
+		{
+			name: "prefix",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default", Empty: true}, {Name: "prod"}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{Prefix: "app-"},
+			expected:   []workspacePair{{Local: "prod", Remote: "app-prod"}, {Local: "stg", Remote: "app-stg"}},
+		},
+		{
+			name: "prefix with default state",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default"}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{Prefix: "app-"},
+			expected:   []workspacePair{{Local: "stg", Remote: "app-stg"}},
+			diags:      []string{"Workspace not migrated"},
+		},
+		{
+			name: "map",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default"}, {Name: "prod"}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{
+				Prefix: "app-",
+				Map:    map[string]string{"default": "app-default", "stg": "app-staging"},
+			},
+			expected: []workspacePair{
+				{Local: "default", Remote: "app-default"},
+				{Local: "prod", Remote: "app-prod"},
+				{Local: "stg", Remote: "app-staging"},
+			},
+		},
+		{
+			name: "map collision",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default", Empty: true}, {Name: "prod"}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{
+				Prefix: "app-",
+				Map:    map[string]string{"stg": "app-prod"},
+			},
+			expected: []workspacePair{{Local: "prod", Remote: "app-prod"}},
+			diags:    []string{"Workspaces 'prod' and 'stg' both map to 'app-prod'"},
+		},
+		{
+			name: "map missing workspace",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default", Empty: true}, {Name: "stg"}},
+			},
+			workspaces: migrate.WorkspaceConfig{
+				Prefix: "app-",
+				Map:    map[string]string{"qa": "app-qa"},
+			},
+			expected: []workspacePair{{Local: "stg", Remote: "app-stg"}},
+			diags:    []string{"Workspace 'qa' in the workspace map does not exist"},
+		},
+		{
+			name: "only default",
+			discovered: state.Workspaces{
+				Current: "default",
+				All:     []state.Workspace{{Name: "default", Empty: true}},
+			},
+			workspaces: migrate.WorkspaceConfig{Prefix: "app-"},
+			expected:   []workspacePair{},
+			diags:      []string{"No workspaces other than 'default' found"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pairs, diags := workspacePairs(&tc.discovered, tc.workspaces)
+			assert.Equal(t, tc.expected, pairs)
+			assert.Equal(t, tc.diags, diagSummaries(diags))
+		})
+	}
+}
+
+func TestReadWorkspaceMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		expected map[string]string
+		diags    []string
+	}{
+		{
+			name:     "hcl",
+			filename: "map.hcl",
+			content: `
+				stg  = "app-staging"
+				prod = "app-production"
+			`,
+			expected: map[string]string{"stg": "app-staging", "prod": "app-production"},
+		},
+		{
+			name:     "json",
+			filename: "map.json",
+			content:  `{"stg": "app-staging"}`,
+			expected: map[string]string{"stg": "app-staging"},
+		},
+		{
+			name:     "not a string",
+			filename: "map.hcl",
+			content: `
+				stg  = "app-staging"
+				prod = ["app-production"]
+			`,
+			expected: map[string]string{"stg": "app-staging"},
+			diags:    []string{"Invalid workspace name"},
+		},
+		{
+			name:     "syntax",
+			filename: "map.hcl",
+			content:  `stg = `,
+			diags:    []string{"Invalid expression"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{tc.filename: tc.content})
+			defer os.RemoveAll(dir)
+
+			m, diags := readWorkspaceMap(filepath.Join(dir, tc.filename))
+			assert.Equal(t, tc.diags, diagSummaries(diags))
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, m)
+			}
+		})
+	}
+}
+
+func TestMergeWorkspaceMap(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"map.hcl": `
+			stg  = "app-staging"
+			prod = "app-production"
+		`,
+		"invalid.hcl": `stg = `,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		path     string
+		flags    map[string]string
+		expected map[string]string
+		diags    []string
+	}{
+		{
+			name:     "none",
+			expected: map[string]string{},
+		},
+		{
+			name:     "flags",
+			flags:    map[string]string{"stg": "app-stg"},
+			expected: map[string]string{"stg": "app-stg"},
+		},
+		{
+			name:     "file",
+			path:     "map.hcl",
+			expected: map[string]string{"stg": "app-staging", "prod": "app-production"},
+		},
+		{
+			name:     "flags override file",
+			path:     "map.hcl",
+			flags:    map[string]string{"stg": "app-stg", "qa": "app-qa"},
+			expected: map[string]string{"stg": "app-stg", "prod": "app-production", "qa": "app-qa"},
+		},
+		{
+			name:  "invalid file",
+			path:  "invalid.hcl",
+			flags: map[string]string{"stg": "app-stg"},
+			diags: []string{"Invalid expression"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tc.path
+			if path != "" {
+				path = filepath.Join(dir, path)
+			}
+
+			m, diags := mergeWorkspaceMap(path, tc.flags)
+			assert.Equal(t, tc.expected, m)
+			assert.Equal(t, tc.diags, diagSummaries(diags))
+		})
+	}
+}
+
+func TestValidateCopyState(t *testing.T) {
+	workspaces := migrate.WorkspaceConfig{
+		Prefix: "app-",
+		Map:    map[string]string{"stg": "app-staging"},
+	}
+
+	err := validateCopyState(&RunCommandConfig{}, workspaces)
+	assert.EqualError(t, err, "workspace map requires --copy-state, since 'terraform init' copies each workspace to its name with the prefix")
+
+	err = validateCopyState(&RunCommandConfig{CopyState: true, Organization: "org", StateSource: stateSourcePull}, workspaces)
+	assert.NoError(t, err)
+
+	err = validateCopyState(&RunCommandConfig{DryRun: true}, workspaces)
+	assert.NoError(t, err)
+
+	err = validateCopyState(&RunCommandConfig{}, migrate.WorkspaceConfig{Prefix: "app-"})
+	assert.NoError(t, err)
+}
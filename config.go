@@ -1,12 +1,53 @@
 package migrate
 
-import "github.com/bendrucker/terraform-cloud-migrate/configwrite"
+import (
+	"regexp"
+
+	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+)
 
 type Config struct {
 	Backend           configwrite.RemoteBackendConfig
 	WorkspaceVariable string
-	TfvarsFilename    string
-	ModulesDir        string
+	// Workspaces are the module's existing workspaces, which the workspace variable is validated against. If empty,
+	// they are read from terraform.tfstate.d.
+	Workspaces     []string
+	TfvarsFilename string
+	// TfvarsUpload deletes tfvars files so their values can be uploaded as workspace variables instead of renaming terraform.tfvars
+	TfvarsUpload bool
+	// ExtractWorkspaceLocals replaces locals that are looked up by terraform.workspace with variables set for each workspace,
+	// as uploaded workspace variables or in tfvars files
+	ExtractWorkspaceLocals bool
+	// WorkspaceTfvarsFiles writes the values of extracted locals to a tfvars file for each workspace instead of
+	// returning them to upload as workspace variables
+	WorkspaceTfvarsFiles bool
+	// VarFiles are additional variable files to upload
+	VarFiles []string
+	// SensitiveVariables matches the names of uploaded variables that are marked sensitive
+	SensitiveVariables *regexp.Regexp
+	// BackendConfig is partial configuration for the existing backend, as passed to 'terraform init -backend-config'
+	BackendConfig []string
+	// ConvertBackendConfig converts backend config files to Terraform Cloud settings instead of deleting them
+	ConvertBackendConfig bool
+	// NoFormat keeps the existing formatting of lines that are not edited instead of formatting changed files
+	NoFormat   bool
+	ModulesDir string
+	// Steps lists the identifiers of the steps to run. All steps run if empty.
+	Steps []string
+}
+
+func (c Config) stepEnabled(id string) bool {
+	if len(c.Steps) == 0 {
+		return true
+	}
+
+	for _, step := range c.Steps {
+		if step == id {
+			return true
+		}
+	}
+
+	return false
 }
 
 type RemoteBackendConfig = configwrite.RemoteBackendConfig
@@ -10,6 +10,11 @@ import (
 
 const (
 	BackendTypeRemote = "remote"
+
+	// BackendStyleBackend configures Terraform Cloud with a "remote" backend block
+	BackendStyleBackend = "backend"
+	// BackendStyleCloud configures Terraform Cloud with a "cloud" block (Terraform 1.1+)
+	BackendStyleCloud = "cloud"
 )
 
 type RemoteBackend struct {
@@ -18,14 +23,39 @@ type RemoteBackend struct {
 }
 
 type RemoteBackendConfig struct {
+	// Style is the type of block to generate, BackendStyleBackend (default) or BackendStyleCloud
+	Style        string
 	Hostname     string
 	Organization string
 	Workspaces   WorkspaceConfig
 }
 
+// Cloud returns whether a "cloud" block will be generated instead of a "remote" backend
+func (c RemoteBackendConfig) Cloud() bool {
+	return c.Style == BackendStyleCloud
+}
+
 type WorkspaceConfig struct {
 	Name   string
 	Prefix string
+	// Tags selects workspaces by tag and is only supported by "cloud" blocks
+	Tags []string
+	// Map renames workspaces of the existing backend. Keys are existing workspace names and values are
+	// Terraform Cloud workspace names, including the prefix.
+	Map map[string]string
+}
+
+// RemoteName returns the name of the Terraform Cloud workspace for a workspace of the existing backend
+func (c WorkspaceConfig) RemoteName(workspace string) string {
+	if name, ok := c.Map[workspace]; ok {
+		return name
+	}
+
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return c.Prefix + workspace
 }
 
 func (b *RemoteBackend) WithWriter(w *Writer) Step {
@@ -39,20 +69,40 @@ func (b *RemoteBackend) Name() string {
 
 // Description returns a description of the step
 func (b *RemoteBackend) Description() string {
+	if b.Config.Cloud() {
+		return `A "cloud" block should be configured for Terraform Cloud, which requires Terraform 1.1+ (https://www.terraform.io/language/settings/terraform-cloud)`
+	}
 	return `A "remote" backend should be configured for Terraform Cloud (https://www.terraform.io/docs/backends/types/remote.html)`
 }
 
-// MultipleWorkspaces returns whether the remote backend will be configured for multiple prefixed workspaces
+// MultipleWorkspaces returns whether the remote backend will be configured for multiple prefixed or tagged workspaces
 func (b *RemoteBackend) MultipleWorkspaces() bool {
-	return b.Config.Workspaces.Prefix != ""
+	return b.Config.Workspaces.Prefix != "" || len(b.Config.Workspaces.Tags) != 0
+}
+
+// Complete checks if the module is already configured for Terraform Cloud in the requested style
+func (b *RemoteBackend) Complete() bool {
+	if b.writer.HasCloud() {
+		return true
+	}
+
+	if b.Config.Cloud() {
+		return false
+	}
+
+	return b.writer.HasBackend() && b.writer.Backend().Type == BackendTypeRemote
 }
 
 // Changes updates the configured backend
 func (b *RemoteBackend) Changes() (Changes, hcl.Diagnostics) {
-	if b.writer.module.Backend.Type == "remote" {
+	if b.Complete() {
 		return Changes{}, nil
 	}
 
+	if diags := b.validate(); diags.HasErrors() {
+		return Changes{}, diags
+	}
+
 	var path string
 	var file *hclwrite.File
 	var diags hcl.Diagnostics
@@ -78,23 +128,70 @@ func (b *RemoteBackend) Changes() (Changes, hcl.Diagnostics) {
 			}
 
 			block.Body().RemoveBlock(child)
+			block.Body().AppendBlock(b.block())
+		}
 
-			remote := block.Body().AppendBlock(hclwrite.NewBlock("backend", []string{"remote"})).Body()
-			remote.SetAttributeValue("hostname", cty.StringVal(b.Config.Hostname))
-			remote.SetAttributeValue("organization", cty.StringVal(b.Config.Organization))
-			remote.AppendNewline()
+	}
 
-			workspaces := remote.AppendBlock(hclwrite.NewBlock("workspaces", nil)).Body()
-			if b.MultipleWorkspaces() {
-				workspaces.SetAttributeValue("prefix", cty.StringVal(b.Config.Workspaces.Prefix))
-			} else {
-				workspaces.SetAttributeValue("name", cty.StringVal(b.Config.Workspaces.Name))
-			}
+	return Changes{path: &Change{File: file}}, diags
+}
+
+func (b *RemoteBackend) validate() hcl.Diagnostics {
+	if b.Config.Cloud() && b.Config.Workspaces.Prefix != "" {
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Workspace prefix not supported",
+				Detail:   `The "cloud" block does not support workspace prefixes. Use workspace tags or a workspace name instead.`,
+			},
 		}
+	}
 
+	if !b.Config.Cloud() && len(b.Config.Workspaces.Tags) != 0 {
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Workspace tags not supported",
+				Detail:   `The "remote" backend does not support workspace tags. Use a "cloud" block, a workspace prefix, or a workspace name instead.`,
+			},
+		}
 	}
 
-	return Changes{path: &Change{File: file}}, diags
+	return nil
+}
+
+// block generates the "backend" or "cloud" block
+func (b *RemoteBackend) block() *hclwrite.Block {
+	var block *hclwrite.Block
+	if b.Config.Cloud() {
+		block = hclwrite.NewBlock("cloud", nil)
+	} else {
+		block = hclwrite.NewBlock("backend", []string{BackendTypeRemote})
+	}
+
+	b.Config.writeSettings(block.Body())
+	return block
+}
+
+// writeSettings writes the hostname, organization, and workspaces settings to a body
+func (c RemoteBackendConfig) writeSettings(body *hclwrite.Body) {
+	body.SetAttributeValue("hostname", cty.StringVal(c.Hostname))
+	body.SetAttributeValue("organization", cty.StringVal(c.Organization))
+	body.AppendNewline()
+
+	workspaces := body.AppendBlock(hclwrite.NewBlock("workspaces", nil)).Body()
+	switch {
+	case len(c.Workspaces.Tags) != 0:
+		tags := make([]cty.Value, len(c.Workspaces.Tags))
+		for i, tag := range c.Workspaces.Tags {
+			tags[i] = cty.StringVal(tag)
+		}
+		workspaces.SetAttributeValue("tags", cty.ListVal(tags))
+	case c.Workspaces.Prefix != "":
+		workspaces.SetAttributeValue("prefix", cty.StringVal(c.Workspaces.Prefix))
+	default:
+		workspaces.SetAttributeValue("name", cty.StringVal(c.Workspaces.Name))
+	}
 }
 
 var _ Step = (*RemoteBackend)(nil)
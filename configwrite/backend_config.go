@@ -0,0 +1,212 @@
+package configwrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// backendConfigPatterns match partial backend configuration files in a module directory
+var backendConfigPatterns = []string{
+	"*.backend.hcl",
+	"backend.hcl",
+	"*.tfbackend",
+	filepath.Join("backend-config", "*.hcl"),
+	filepath.Join("backend-config", "*.tfbackend"),
+}
+
+// SetBackendConfig sets partial backend configuration, as passed to 'terraform init -backend-config'. Each argument
+// is a key=value pair or a file relative to the module. Later arguments take precedence over earlier ones and
+// over the backend block.
+func (w *Writer) SetBackendConfig(args []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	w.backendConfig = make(map[string]cty.Value)
+	w.backendConfigFiles = nil
+
+	for _, arg := range args {
+		if eq := strings.Index(arg, "="); eq != -1 {
+			w.backendConfig[arg[:eq]] = cty.StringVal(arg[eq+1:])
+			continue
+		}
+
+		path := arg
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(w.Dir(), path)
+		}
+
+		if exists, _ := afero.Exists(w.fs, path); !exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Backend config file not found",
+				Detail:   fmt.Sprintf("The backend config file %s does not exist.", arg),
+				Subject:  &hcl.Range{Filename: path},
+			})
+			continue
+		}
+
+		values, vDiags := w.backendConfigFile(path)
+		diags = append(diags, vDiags...)
+		for name, value := range values {
+			w.backendConfig[name] = value
+		}
+		w.backendConfigFiles = append(w.backendConfigFiles, path)
+	}
+
+	return diags
+}
+
+// backendConfigFile reads the arguments in a partial backend configuration file. Nested blocks are ignored.
+func (w *Writer) backendConfigFile(path string) (map[string]cty.Value, hcl.Diagnostics) {
+	body, diags := w.parser.LoadHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attrs := make(hcl.Attributes)
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		for name, attr := range syntaxBody.Attributes {
+			attrs[name] = attr.AsHCLAttribute()
+		}
+	} else {
+		var aDiags hcl.Diagnostics
+		attrs, aDiags = body.JustAttributes()
+		diags = append(diags, aDiags...)
+	}
+
+	values := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		value, vDiags := attr.Expr.Value(nil)
+		diags = append(diags, vDiags...)
+		if vDiags.HasErrors() {
+			continue
+		}
+		values[name] = value
+	}
+
+	return values, diags
+}
+
+// BackendConfigFiles removes partial configuration files for the previous backend, or converts them to
+// partial configuration for Terraform Cloud
+type BackendConfigFiles struct {
+	writer *Writer
+	// Convert replaces the contents of each file with the Terraform Cloud settings instead of deleting it
+	Convert       bool
+	RemoteBackend RemoteBackendConfig
+}
+
+func (s *BackendConfigFiles) WithWriter(w *Writer) Step {
+	s.writer = w
+	return s
+}
+
+func (s *BackendConfigFiles) Name() string {
+	if s.Convert {
+		return "Convert backend config files"
+	}
+	return "Remove backend config files"
+}
+
+// Description returns a description of the step
+func (s *BackendConfigFiles) Description() string {
+	return `Partial backend configuration files (-backend-config) configure the previous backend and are not used by Terraform Cloud (https://www.terraform.io/docs/backends/config.html#partial-configuration)`
+}
+
+// Complete checks if any backend config files remain that have not been converted
+func (s *BackendConfigFiles) Complete() bool {
+	for _, path := range s.paths() {
+		if !s.converted(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// paths returns the backend config files in the module directory, including files passed to SetBackendConfig
+func (s *BackendConfigFiles) paths() []string {
+	dir := s.writer.Dir()
+	seen := make(map[string]bool)
+	var paths []string
+
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		if info, err := s.writer.fs.Stat(path); err != nil || info.IsDir() {
+			return
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+
+	for _, pattern := range backendConfigPatterns {
+		matches, _ := afero.Glob(s.writer.fs, filepath.Join(dir, pattern))
+		for _, path := range matches {
+			add(path)
+		}
+	}
+
+	for _, path := range s.writer.backendConfigFiles {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			add(path)
+		}
+	}
+
+	return paths
+}
+
+// ConvertedFiles returns the backend config files that contain Terraform Cloud settings after the changes are
+// written, which are passed to 'terraform init -backend-config'. It is empty unless files are converted.
+func (s *BackendConfigFiles) ConvertedFiles() []string {
+	if !s.Convert {
+		return nil
+	}
+	return s.paths()
+}
+
+// converted returns true if a file already contains Terraform Cloud settings
+func (s *BackendConfigFiles) converted(path string) bool {
+	if !s.Convert {
+		return false
+	}
+
+	values, diags := s.writer.backendConfigFile(path)
+	_, ok := values["organization"]
+	return !diags.HasErrors() && ok
+}
+
+// Changes deletes or converts each backend config file
+func (s *BackendConfigFiles) Changes() (Changes, hcl.Diagnostics) {
+	changes := Changes{}
+	var diags hcl.Diagnostics
+
+	for _, path := range s.paths() {
+		if s.converted(path) {
+			continue
+		}
+
+		file, fDiags := s.writer.File(path)
+		diags = append(diags, fDiags...)
+		if file == nil {
+			continue
+		}
+
+		if !s.Convert {
+			changes[path] = &Change{File: file, Delete: true}
+			continue
+		}
+
+		file.Body().Clear()
+		s.RemoteBackend.writeSettings(file.Body())
+		changes[path] = &Change{File: file}
+	}
+
+	return changes, diags
+}
+
+var _ Step = (*BackendConfigFiles)(nil)
@@ -0,0 +1,156 @@
+package configwrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestWriterSetBackendConfig(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"backend.tf": `
+			terraform {
+				backend "s3" {
+					region = "us-east-1"
+				}
+			}
+		`,
+		"env/prod.backend.hcl": `
+			bucket = "terraform-state"
+			key    = "prod.tfstate"
+		`,
+	})
+
+	diags := writer.SetBackendConfig([]string{"env/prod.backend.hcl", "key=app/prod.tfstate", "missing.hcl"})
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Backend config file not found", diags[0].Summary)
+
+	assert.Equal(t, map[string]cty.Value{
+		"region": cty.StringVal("us-east-1"),
+		"bucket": cty.StringVal("terraform-state"),
+		"key":    cty.StringVal("app/prod.tfstate"),
+	}, writer.backendStateConfig().Values)
+}
+
+func TestRemoteState_backendConfig(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"backend.tf": `
+			terraform {
+				backend "s3" {}
+			}
+		`,
+		"prod.backend.hcl": `
+			bucket = "terraform-state"
+			key    = "terraform.tfstate"
+			region = "us-east-1"
+		`,
+		"dependent/a/backend.tf": `
+			data "terraform_remote_state" "match" {
+				backend = "s3"
+
+				config = {
+					bucket = "terraform-state"
+					key    = "terraform.tfstate"
+				}
+			}
+		`,
+	})
+
+	step := &RemoteState{Path: "dependent/"}
+	step.WithWriter(writer)
+
+	assert.True(t, step.Complete())
+	assert.Empty(t, writer.SetBackendConfig([]string{"prod.backend.hcl"}))
+	assert.False(t, step.Complete())
+
+	sources, diags := step.dependentSources()
+	assert.Empty(t, diags)
+	assert.Len(t, sources, 1)
+}
+
+func TestBackendConfigFiles(t *testing.T) {
+	in := map[string]string{
+		"backend.tf": `
+			terraform {
+				backend "s3" {}
+			}
+		`,
+		"prod.backend.hcl": `
+			bucket = "terraform-state"
+		`,
+		"staging.tfbackend": `
+			bucket = "terraform-state-staging"
+		`,
+		"backend-config/dev.hcl": `
+			bucket = "terraform-state-dev"
+		`,
+		"backend-config/qa.tfbackend": `
+			bucket = "terraform-state-qa"
+		`,
+		"backend-config/README.md": "Backend configuration for each environment",
+		"main.tf": `
+			locals {}
+		`,
+	}
+
+	testStepChanges(t, stepTests{
+		{
+			name:     "delete",
+			step:     &BackendConfigFiles{},
+			in:       in,
+			expected: map[string]string{},
+			deleted:  []string{"backend-config/dev.hcl", "backend-config/qa.tfbackend", "prod.backend.hcl", "staging.tfbackend"},
+		},
+		{
+			name: "convert",
+			step: &BackendConfigFiles{
+				Convert: true,
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "app.terraform.io",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Prefix: "app-",
+					},
+				},
+			},
+			in: map[string]string{
+				"prod.backend.hcl": `
+					# production
+					bucket = "terraform-state"
+				`,
+				"converted.backend.hcl": `
+					organization = "org"
+				`,
+				"main.tf": `
+					locals {}
+				`,
+			},
+			expected: map[string]string{
+				"prod.backend.hcl": `
+					hostname     = "app.terraform.io"
+					organization = "org"
+
+					workspaces {
+					  prefix = "app-"
+					}
+				`,
+			},
+		},
+	})
+}
+
+func TestBackendConfigFiles_ConvertedFiles(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf":                  "",
+		"prod.backend.hcl":         `bucket = "terraform-state"`,
+		"backend-config/README.md": "",
+	})
+
+	step := &BackendConfigFiles{}
+	step.WithWriter(writer)
+	assert.Empty(t, step.ConvertedFiles())
+
+	step.Convert = true
+	assert.Equal(t, []string{"prod.backend.hcl"}, step.ConvertedFiles())
+}
@@ -2,6 +2,8 @@ package configwrite
 
 import (
 	"testing"
+
+	"github.com/hashicorp/hcl/v2"
 )
 
 func TestRemoteBackend(t *testing.T) {
@@ -107,5 +109,177 @@ func TestRemoteBackend(t *testing.T) {
 			},
 			expected: map[string]string{},
 		},
+		{
+			name: "cloud",
+			step: &RemoteBackend{
+				Config: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Name: "ws",
+					},
+				},
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"backend.tf": `
+					terraform {
+						cloud {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces {
+								name = "ws"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "cloud/tags",
+			step: &RemoteBackend{
+				Config: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Tags: []string{"app", "networking"},
+					},
+				},
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"backend.tf": `
+					terraform {
+						cloud {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces {
+								tags = ["app", "networking"]
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "cloud/from_remote",
+			step: &RemoteBackend{
+				Config: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Name: "ws",
+					},
+				},
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "remote" {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces {
+								name = "ws"
+							}
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"backend.tf": `
+					terraform {
+						cloud {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces {
+								name = "ws"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "cloud/complete",
+			step: &RemoteBackend{
+				Config: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Tags: []string{"app"},
+					},
+				},
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						cloud {
+							organization = "org"
+					
+							workspaces {
+								tags = ["app"]
+							}
+						}
+					}
+				`,
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "cloud/prefix",
+			step: &RemoteBackend{
+				Config: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Prefix: "ws-",
+					},
+				},
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {}
+					}
+				`,
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Workspace prefix not supported",
+					Detail:   `The "cloud" block does not support workspace prefixes. Use workspace tags or a workspace name instead.`,
+				},
+			},
+		},
 	})
 }
@@ -2,18 +2,48 @@ package configwrite
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
 type Change struct {
+	// File is the new contents of the file, which may be nil when the file is deleted
 	File   *hclwrite.File
 	Rename string
+	// Delete removes the file instead of writing it
+	Delete bool
+	// KeepFormatting keeps the existing formatting of lines that the change does not edit instead of formatting the whole file
+	KeepFormatting bool
+	// Steps records the contribution of each step to the change, in order
+	Steps []StepChange
 }
 
+// StepChange is a single step's contribution to a change
+type StepChange struct {
+	Step string
+	// Diff is a unified diff of the file before and after the step
+	Diff string
+}
+
+// StepNames returns the names of the steps that contributed to the change
+func (c *Change) StepNames() []string {
+	names := make([]string, len(c.Steps))
+	for i, step := range c.Steps {
+		names[i] = step.Step
+	}
+	return names
+}
+
+// Destination returns the path the change is written to, or /dev/null if the file is deleted
 func (c *Change) Destination(path string) string {
+	if c.Delete {
+		return devNull
+	}
+
 	if c.Rename == "" {
 		return path
 	}
@@ -21,20 +51,114 @@ func (c *Change) Destination(path string) string {
 	return filepath.Join(filepath.Dir(path), c.Rename)
 }
 
+// Contents returns the bytes written for the file currently at path, or nil if the file is deleted
+func (c *Change) Contents(path string) ([]byte, error) {
+	if c.Delete {
+		return nil, nil
+	}
+
+	if !c.KeepFormatting {
+		return c.formatted(), nil
+	}
+
+	original, err := readOriginal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return keepFormatting(original, c.formatted()), nil
+}
+
+// formatted returns the file after the change with canonical formatting
+func (c *Change) formatted() []byte {
+	return hclwrite.Format(c.File.Bytes())
+}
+
+// WriteFile writes the change to its destination atomically
 func (c *Change) WriteFile(path string) error {
-	file, err := os.OpenFile(c.Destination(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	return Changes{path: c}.WriteFiles()
+}
+
+// stage writes the change to a temporary file in the destination directory and validates it
+func (c *Change) stage(path string) (*stagedFile, error) {
+	destination := c.Destination(path)
+
+	mode := os.FileMode(0644)
+	var original []byte
+	if info, err := os.Stat(destination); err == nil {
+		mode = info.Mode().Perm()
+		if original, err = ioutil.ReadFile(destination); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(destination), "."+filepath.Base(destination)+".*.tmp")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	staged := &stagedFile{
+		path:        path,
+		destination: destination,
+		tmp:         tmp.Name(),
+		original:    original,
+		mode:        mode,
+	}
+
+	b, err := c.Contents(path)
+	if err == nil {
+		_, err = tmp.Write(b)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(staged.tmp, mode)
+	}
+	if err == nil {
+		err = staged.validate()
 	}
-	defer file.Close()
 
-	_, err = c.File.WriteTo(file)
+	if err != nil {
+		os.Remove(staged.tmp)
+		return nil, err
+	}
+
+	return staged, nil
+}
+
+type stagedFile struct {
+	path        string
+	destination string
+	tmp         string
+
+	// original is the content of the destination before it is replaced, or nil if it did not exist
+	original []byte
+	mode     os.FileMode
+}
+
+// restore puts back the original destination after the staged file was moved into place
+func (s *stagedFile) restore() error {
+	if s.original == nil {
+		return os.Remove(s.destination)
+	}
+	return ioutil.WriteFile(s.destination, s.original, s.mode)
+}
+
+// validate re-reads the staged file and checks that it parses
+func (s *stagedFile) validate() error {
+	b, err := ioutil.ReadFile(s.tmp)
 	if err != nil {
 		return err
 	}
 
-	if c.Rename != "" {
-		return os.Remove(path)
+	if _, diags := hclwrite.ParseConfig(b, s.destination, hcl.InitialPos); diags.HasErrors() {
+		return fmt.Errorf("invalid configuration generated for %s: %v", s.destination, diags)
 	}
 
 	return nil
@@ -43,19 +167,91 @@ func (c *Change) WriteFile(path string) error {
 // Changes is a map of changed file objects that should be written to prepare the module for Terraform Cloud
 type Changes map[string]*Change
 
+// Add adds a change. If the path is already changed, the changes are merged when they edit the same file
+// object. Different file objects or different renames for the same path are conflicts.
 func (c Changes) Add(path string, change *Change) error {
-	if existing, ok := c[path]; ok && existing.Rename != "" && change.Rename != "" {
+	existing, ok := c[path]
+	if !ok {
+		c[path] = change
+		return nil
+	}
+
+	if existing.Rename != "" && change.Rename != "" && existing.Rename != change.Rename {
 		return &renameCollisionError{Existing: existing.Rename, Proposed: change.Rename}
 	}
 
-	c[path] = change
+	if existing.File != change.File || existing.Delete != change.Delete {
+		return &fileConflictError{Path: path}
+	}
+
+	rename := existing.Rename
+	if rename == "" {
+		rename = change.Rename
+	}
+
+	steps := make([]StepChange, 0, len(existing.Steps)+len(change.Steps))
+	c[path] = &Change{
+		File:           existing.File,
+		Rename:         rename,
+		Delete:         existing.Delete,
+		KeepFormatting: existing.KeepFormatting || change.KeepFormatting,
+		Steps:          append(append(steps, existing.Steps...), change.Steps...),
+	}
 
 	return nil
 }
 
+// rename moves staged files into place and is replaced in tests
+var rename = os.Rename
+
+// WriteFiles writes all changes as a transaction. Every file is staged and validated before any are moved into place
+// and deleted files are removed last.
 func (c Changes) WriteFiles() error {
-	for path, change := range c {
-		if err := change.WriteFile(path); err != nil {
+	staged := make([]*stagedFile, 0, len(c))
+	defer func() {
+		for _, s := range staged {
+			os.Remove(s.tmp)
+		}
+	}()
+
+	var deleted []string
+	for _, path := range c.Paths() {
+		if c[path].Delete {
+			deleted = append(deleted, path)
+			continue
+		}
+
+		s, err := c[path].stage(path)
+		if err != nil {
+			return fmt.Errorf("failed to stage %s: %v", path, err)
+		}
+		staged = append(staged, s)
+	}
+
+	for i, s := range staged {
+		if err := rename(s.tmp, s.destination); err != nil {
+			// files that were already moved into place are restored so that no changes are partially applied
+			for _, applied := range staged[:i] {
+				if rerr := applied.restore(); rerr != nil {
+					return fmt.Errorf("%v, and failed to restore %s: %v", err, applied.destination, rerr)
+				}
+			}
+			return err
+		}
+	}
+
+	for _, s := range staged {
+		if s.destination == s.path {
+			continue
+		}
+
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	for _, path := range deleted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
@@ -71,3 +267,11 @@ type renameCollisionError struct {
 func (e *renameCollisionError) Error() string {
 	return fmt.Sprintf("cannot rename to '%s', already renamed to '%s'", e.Proposed, e.Existing)
 }
+
+type fileConflictError struct {
+	Path string
+}
+
+func (e *fileConflictError) Error() string {
+	return fmt.Sprintf("conflicting changes to %s", e.Path)
+}
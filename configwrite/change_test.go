@@ -0,0 +1,144 @@
+package configwrite
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangesWriteFiles(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf":             "a = 1\n",
+		"terraform.tfvars":    "foo = \"bar\"\n",
+		"secrets.auto.tfvars": "password = \"hunter2\"\n",
+	})
+	defer os.RemoveAll(dir)
+
+	changes := Changes{
+		filepath.Join(dir, "main.tf"):             &Change{File: testParseFile(t, "a = 2\n")},
+		filepath.Join(dir, "terraform.tfvars"):    &Change{File: testParseFile(t, "foo = \"bar\"\n"), Rename: "terraform.auto.tfvars"},
+		filepath.Join(dir, "variables.tf"):        &Change{File: testParseFile(t, "variable \"environment\" {}\n")},
+		filepath.Join(dir, "secrets.auto.tfvars"): &Change{File: testParseFile(t, "password = \"hunter2\"\n"), Delete: true},
+	}
+
+	if err := changes.WriteFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]string{
+		"main.tf":               "a = 2\n",
+		"terraform.auto.tfvars": "foo = \"bar\"\n",
+		"variables.tf":          "variable \"environment\" {}\n",
+	}, testReadDir(t, dir))
+}
+
+func TestChangesWriteFiles_invalid(t *testing.T) {
+	files := map[string]string{
+		"main.tf":          "a = 1\n",
+		"terraform.tfvars": "foo = \"bar\"\n",
+	}
+	dir := testTempDir(t, files)
+	defer os.RemoveAll(dir)
+
+	invalid := hclwrite.NewEmptyFile()
+	invalid.Body().AppendUnstructuredTokens(hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+	})
+
+	changes := Changes{
+		filepath.Join(dir, "main.tf"):          &Change{File: testParseFile(t, "a = 2\n")},
+		filepath.Join(dir, "terraform.tfvars"): &Change{File: testParseFile(t, "foo = \"bar\"\n"), Rename: "terraform.auto.tfvars"},
+		filepath.Join(dir, "variables.tf"):     &Change{File: invalid},
+	}
+
+	assert.Error(t, changes.WriteFiles())
+	assert.Equal(t, files, testReadDir(t, dir), "no files are written and staged files are removed")
+}
+
+func TestChangesWriteFiles_missingDir(t *testing.T) {
+	files := map[string]string{
+		"main.tf": "a = 1\n",
+	}
+	dir := testTempDir(t, files)
+	defer os.RemoveAll(dir)
+
+	changes := Changes{
+		filepath.Join(dir, "main.tf"):            &Change{File: testParseFile(t, "a = 2\n")},
+		filepath.Join(dir, "missing", "main.tf"): &Change{File: testParseFile(t, "b = 2\n")},
+	}
+
+	assert.Error(t, changes.WriteFiles())
+	assert.Equal(t, files, testReadDir(t, dir))
+}
+
+func TestChangesWriteFiles_renameFailed(t *testing.T) {
+	files := map[string]string{
+		"a.tf":             "b = 0\n",
+		"main.tf":          "a = 1\n",
+		"terraform.tfvars": "foo = \"bar\"\n",
+	}
+	dir := testTempDir(t, files)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	rename = func(from, to string) error {
+		calls++
+		if calls == 2 {
+			return errors.New("rename failed")
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { rename = os.Rename }()
+
+	changes := Changes{
+		filepath.Join(dir, "a.tf"):             &Change{File: testParseFile(t, "b = 1\n")},
+		filepath.Join(dir, "main.tf"):          &Change{File: testParseFile(t, "a = 2\n")},
+		filepath.Join(dir, "terraform.tfvars"): &Change{File: testParseFile(t, "foo = \"bar\"\n"), Rename: "terraform.auto.tfvars"},
+	}
+
+	assert.EqualError(t, changes.WriteFiles(), "rename failed")
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, files, testReadDir(t, dir), "files that were moved into place are restored")
+}
+
+func testTempDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "configwrite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func testReadDir(t *testing.T, dir string) map[string]string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(map[string]string)
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[info.Name()] = string(b)
+	}
+
+	return out
+}
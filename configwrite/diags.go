@@ -8,7 +8,7 @@ func errorDiags(diags hcl.Diagnostics) hcl.Diagnostics {
 		if diag.Severity != hcl.DiagError {
 			continue
 		}
-		result = append(result, diags...)
+		result = append(result, diag)
 	}
 	return result
 }
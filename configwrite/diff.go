@@ -0,0 +1,120 @@
+package configwrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const devNull = "/dev/null"
+
+// Diff returns a unified diff between the file currently at path and the changed file
+func (c *Change) Diff(path string) (string, error) {
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	from := path
+	if os.IsNotExist(err) {
+		from = devNull
+	}
+
+	after, err := c.Contents(path)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(from, c.Destination(path), before, after, c.Rename != "")
+}
+
+// readOriginal reads the file currently at path, returning nil if it does not exist
+func readOriginal(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Paths returns the changed paths in sorted order
+func (c Changes) Paths() []string {
+	paths := make([]string, 0, len(c))
+	for path := range c {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Diff returns the unified diffs of all changes, ordered by path
+func (c Changes) Diff() (string, error) {
+	var sb strings.Builder
+
+	for _, path := range c.Paths() {
+		diff, err := c[path].Diff(path)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(diff)
+	}
+
+	return sb.String(), nil
+}
+
+// StepDiff returns the unified diffs of a single step's changes, ordered by path. Each diff is relative to the
+// files as changed by the steps before it.
+func (c Changes) StepDiff(step string) string {
+	var sb strings.Builder
+
+	for _, path := range c.Paths() {
+		for _, change := range c[path].Steps {
+			if change.Step == step {
+				sb.WriteString(change.Diff)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func unifiedDiff(from, to string, before, after []byte, rename bool) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !rename {
+		return diff, nil
+	}
+
+	header := fmt.Sprintf("rename from %s\nrename to %s\n", from, to)
+	if diff == "" {
+		return header, nil
+	}
+
+	return header + diff, nil
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,64 @@
+package configwrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangesDiff(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf":             "a = 1\nb = 2\n",
+		"terraform.tfvars":    "foo = \"bar\"\n",
+		"secrets.auto.tfvars": "password = \"hunter2\"\n",
+	})
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "main.tf")
+	tfvars := filepath.Join(dir, "terraform.tfvars")
+	backend := filepath.Join(dir, "backend.tf")
+	secrets := filepath.Join(dir, "secrets.auto.tfvars")
+
+	changes := Changes{
+		main:    &Change{File: testParseFile(t, "a = 1\nb = 3\n")},
+		tfvars:  &Change{File: testParseFile(t, "foo = \"bar\"\n"), Rename: "terraform.auto.tfvars"},
+		backend: &Change{File: testParseFile(t, "c = 4\n")},
+		secrets: &Change{File: testParseFile(t, "password = \"hunter2\"\n"), Delete: true},
+	}
+
+	diff, err := changes.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, trimTestConfig(`
+		--- /dev/null
+		+++ `+backend+`
+		@@ -0,0 +1 @@
+		+c = 4
+		--- `+main+`
+		+++ `+main+`
+		@@ -1,2 +1,2 @@
+		 a = 1
+		-b = 2
+		+b = 3
+		--- `+secrets+`
+		+++ /dev/null
+		@@ -1 +0,0 @@
+		-password = "hunter2"
+		rename from `+tfvars+`
+		rename to `+filepath.Join(dir, "terraform.auto.tfvars")+`
+	`), diff)
+}
+
+func testParseFile(t *testing.T, src string) *hclwrite.File {
+	file, diags := hclwrite.ParseConfig([]byte(src), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	return file
+}
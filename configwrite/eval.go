@@ -0,0 +1,130 @@
+package configwrite
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/terraform/lang"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// EvalContext returns a context for evaluating the module's expressions without running Terraform. Variables are set
+// from tfvars files and defaults, locals are set if they can be resolved from other known values, and Terraform's
+// functions are available. Everything else, including terraform.workspace, is unknown.
+func (w *Writer) EvalContext() (*hcl.EvalContext, hcl.Diagnostics) {
+	values, diags := w.tfvarsValues()
+
+	vars := make(map[string]cty.Value, len(w.module.Variables))
+	for name, variable := range w.module.Variables {
+		value, ok := values[name]
+		if !ok {
+			value = variable.Default
+		}
+
+		if value == cty.NilVal || value.IsNull() {
+			value = cty.UnknownVal(variable.Type)
+		} else if converted, err := convert.Convert(value, variable.Type); err == nil {
+			value = converted
+		}
+
+		vars[name] = value
+	}
+
+	dir := w.Dir()
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(vars),
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal(dir),
+				"root":   cty.StringVal(dir),
+				"cwd":    cty.StringVal(dir),
+			}),
+			"terraform": cty.ObjectVal(map[string]cty.Value{
+				"workspace": cty.UnknownVal(cty.String),
+			}),
+		},
+		Functions: (&lang.Scope{BaseDir: dir, PureOnly: true}).Functions(),
+	}
+
+	ctx.Variables["local"] = cty.ObjectVal(w.locals(ctx))
+
+	return ctx, diags
+}
+
+// locals resolves the module's locals. Locals can refer to each other, so each pass resolves the locals whose
+// references were resolved by the previous pass. Locals that cannot be resolved are unknown.
+func (w *Writer) locals(ctx *hcl.EvalContext) map[string]cty.Value {
+	locals := make(map[string]cty.Value, len(w.module.Locals))
+	for name := range w.module.Locals {
+		locals[name] = cty.DynamicVal
+	}
+
+	for resolved := true; resolved; {
+		resolved = false
+		ctx.Variables["local"] = cty.ObjectVal(locals)
+
+		for name, local := range w.module.Locals {
+			if locals[name].IsWhollyKnown() {
+				continue
+			}
+
+			value, diags := local.Expr.Value(ctx)
+			if diags.HasErrors() || !value.IsWhollyKnown() {
+				continue
+			}
+
+			locals[name] = value
+			resolved = true
+		}
+	}
+
+	return locals
+}
+
+// tfvarsFiles returns the variable files that Terraform loads automatically, in the order they are loaded
+func (w *Writer) tfvarsFiles() ([]string, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	var paths []string
+
+	for _, name := range []string{TfvarsFilename, TfvarsFilename + ".json"} {
+		path := filepath.Join(w.Dir(), name)
+		if exists, _ := afero.Exists(w.fs, path); exists {
+			paths = append(paths, path)
+		}
+	}
+
+	var auto []string
+	for _, pattern := range []string{"*.auto.tfvars", "*.auto.tfvars.json"} {
+		matches, err := afero.Glob(w.fs, filepath.Join(w.Dir(), pattern))
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "file read error",
+				Detail:   "files matching " + pattern + " could not be listed: " + err.Error(),
+			})
+		}
+		auto = append(auto, matches...)
+	}
+	sort.Strings(auto)
+
+	return append(paths, auto...), diags
+}
+
+// tfvarsValues reads the values from the variable files that Terraform loads automatically
+func (w *Writer) tfvarsValues() (map[string]cty.Value, hcl.Diagnostics) {
+	paths, diags := w.tfvarsFiles()
+	values := make(map[string]cty.Value)
+
+	for _, path := range paths {
+		file, fDiags := w.parser.LoadValuesFile(path)
+		diags = append(diags, fDiags...)
+		for name, value := range file {
+			values[name] = value
+		}
+	}
+
+	return values, diags
+}
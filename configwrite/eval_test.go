@@ -0,0 +1,52 @@
+package configwrite
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestWriterEvalContext(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf": `
+			variable "name" {
+				default = "app"
+			}
+
+			variable "env" {}
+
+			variable "unset" {}
+
+			locals {
+				key    = "${local.prefix}/terraform.tfstate"
+				prefix = "${upper(var.name)}-${var.env}"
+				other  = var.unset
+			}
+		`,
+		"terraform.tfvars": `
+			env = "prod"
+		`,
+	})
+
+	ctx, diags := writer.EvalContext()
+	assert.Empty(t, diags)
+
+	for src, expected := range map[string]cty.Value{
+		`local.key`:           cty.StringVal("APP-prod/terraform.tfstate"),
+		`var.name`:            cty.StringVal("app"),
+		`local.other`:         cty.DynamicVal,
+		`terraform.workspace`: cty.UnknownVal(cty.String),
+	} {
+		expr, pDiags := hclsyntax.ParseExpression([]byte(src), "test.tf", hcl.InitialPos)
+		if pDiags.HasErrors() {
+			t.Fatal(pDiags)
+		}
+
+		value, vDiags := expr.Value(ctx)
+		assert.Empty(t, vDiags, src)
+		assert.True(t, expected.RawEquals(value), "%s: expected %#v, got %#v", src, expected, value)
+	}
+}
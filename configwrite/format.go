@@ -0,0 +1,110 @@
+package configwrite
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// KeepFormatting disables formatting for every change so that lines the migration does not edit keep their
+// existing formatting
+func (c Changes) KeepFormatting() {
+	for _, change := range c {
+		change.KeepFormatting = true
+	}
+}
+
+// Reformatted returns warnings for files that formatting changes beyond the migration edits because they were
+// not formatted canonically
+func (c Changes) Reformatted() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, path := range c.Paths() {
+		change := c[path]
+		if change.Delete || change.KeepFormatting {
+			continue
+		}
+
+		// edits to a canonical file can realign neighboring lines, which is not a formatting change
+		original, err := readOriginal(path)
+		if err != nil || original == nil || bytes.Equal(original, hclwrite.Format(original)) {
+			continue
+		}
+
+		after := change.formatted()
+		if bytes.Equal(after, keepFormatting(original, after)) {
+			continue
+		}
+
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "File reformatted",
+			Detail:   fmt.Sprintf("%s is not formatted canonically, so formatting changes are made in addition to the migration edits. Disable formatting to keep the existing formatting of lines that are not edited.", path),
+			Subject:  &hcl.Range{Filename: path},
+		})
+	}
+
+	return diags
+}
+
+// keepFormatting returns the formatted file with each line that has the same tokens as the original, differing
+// only in the whitespace between them, replaced by the original line
+func keepFormatting(original, formatted []byte) []byte {
+	if original == nil {
+		return formatted
+	}
+
+	a := strings.SplitAfter(string(original), "\n")
+	b := strings.SplitAfter(string(formatted), "\n")
+
+	matcher := difflib.NewMatcherWithJunk(lineTokens(original, len(a)), lineTokens(formatted, len(b)), false, nil)
+
+	var out strings.Builder
+	for _, op := range matcher.GetOpCodes() {
+		lines := b[op.J1:op.J2]
+		if op.Tag == 'e' {
+			lines = a[op.I1:op.I2]
+		}
+
+		for _, line := range lines {
+			out.WriteString(line)
+		}
+	}
+
+	return []byte(out.String())
+}
+
+// lineTokens returns a key for each line made of the tokens on it, ignoring whitespace and newlines between
+// tokens. Tokens that span lines, such as heredoc and multi-line comments, are part of the key for each of their
+// lines so that whitespace inside strings and comments is compared.
+func lineTokens(src []byte, n int) []string {
+	keys := make([][]string, n)
+
+	tokens, _ := hclsyntax.LexConfig(src, "", hcl.InitialPos)
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenNewline || token.Type == hclsyntax.TokenEOF {
+			continue
+		}
+
+		start, end := token.Range.Start.Line, token.Range.End.Line
+		// a token that ends with a newline ends at the start of the next line
+		if end > start && token.Range.End.Column == 1 {
+			end--
+		}
+
+		for line := start; line <= end && line <= n; line++ {
+			keys[line-1] = append(keys[line-1], string(token.Bytes))
+		}
+	}
+
+	out := make([]string, n)
+	for i, key := range keys {
+		out[i] = strings.Join(key, "\x00")
+	}
+	return out
+}
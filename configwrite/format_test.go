@@ -0,0 +1,107 @@
+package configwrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepFormatting(t *testing.T) {
+	original := "a=1\nb    = 2\n\nlocals {\n    c = 3\n}\n"
+	formatted := "a = 1\nb = 2\n\nlocals {\n  c = 3\n  d = 4\n}\n"
+
+	assert.Equal(t, "a=1\nb    = 2\n\nlocals {\n    c = 3\n  d = 4\n}\n", string(keepFormatting([]byte(original), []byte(formatted))))
+	assert.Equal(t, formatted, string(keepFormatting(nil, []byte(formatted))))
+
+	// whitespace inside strings, heredocs, and comments is part of the line's tokens
+	original = "a  =  \"x  y\"\nb = <<EOT\n  one\nEOT\n/* c  d\n  e */\n"
+	formatted = "a = \"x y\"\nb = <<EOT\n    one\nEOT\n/* c d\n  e */\n"
+	assert.Equal(t, formatted, string(keepFormatting([]byte(original), []byte(formatted))))
+
+	original = "a  =  \"x  y\"\nb = <<EOT\n  one\nEOT\n"
+	formatted = "a = \"x  y\"\nb = <<EOT\n  one\nEOT\nc = 1\n"
+	assert.Equal(t, original+"c = 1\n", string(keepFormatting([]byte(original), []byte(formatted))))
+}
+
+func TestChangesFormat(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf":      "a=1\n",
+		"variables.tf": "variable \"a\" {}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "main.tf")
+	variables := filepath.Join(dir, "variables.tf")
+
+	changes := Changes{
+		main:      &Change{File: testParseFile(t, "a=1\nb=2\n")},
+		variables: &Change{File: testParseFile(t, "variable \"a\" {}\nvariable \"b\" {}\n")},
+	}
+
+	assert.Equal(t, hcl.Diagnostics{
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "File reformatted",
+			Detail:   main + " is not formatted canonically, so formatting changes are made in addition to the migration edits. Disable formatting to keep the existing formatting of lines that are not edited.",
+			Subject:  &hcl.Range{Filename: main},
+		},
+	}, changes.Reformatted())
+
+	b, err := changes[main].Contents(main)
+	assert.NoError(t, err)
+	assert.Equal(t, "a = 1\nb = 2\n", string(b))
+
+	changes.KeepFormatting()
+	assert.Empty(t, changes.Reformatted())
+
+	b, err = changes[main].Contents(main)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1\nb = 2\n", string(b))
+
+	if err := changes.WriteFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]string{
+		"main.tf":      "a=1\nb = 2\n",
+		"variables.tf": "variable \"a\" {}\nvariable \"b\" {}\n",
+	}, testReadDir(t, dir))
+}
+
+func TestChangesReformatted_canonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		edited   string
+	}{
+		{
+			name:     "blocks",
+			original: "a {\n  b {\n    c = 1\n  }\n}\n\nd {\n  e = 2\n}\n",
+			edited:   "a {\n  b {\n    c = 1\n  }\n}\n",
+		},
+		{
+			name:     "alignment",
+			original: "a    = 1\nbbbb = 2\n",
+			edited:   "a = 1\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{
+				"main.tf": tc.original,
+			})
+			defer os.RemoveAll(dir)
+
+			main := filepath.Join(dir, "main.tf")
+			changes := Changes{
+				main: &Change{File: testParseFile(t, tc.edited)},
+			}
+
+			assert.Empty(t, changes.Reformatted())
+		})
+	}
+}
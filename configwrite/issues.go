@@ -0,0 +1,189 @@
+package configwrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fileFunctions are functions that read files from a path argument
+var fileFunctions = map[string]bool{
+	"file":             true,
+	"filebase64":       true,
+	"filebase64sha256": true,
+	"filebase64sha512": true,
+	"fileexists":       true,
+	"filemd5":          true,
+	"fileset":          true,
+	"filesha1":         true,
+	"filesha256":       true,
+	"filesha512":       true,
+	"templatefile":     true,
+}
+
+// pathContext evaluates path.* references relative to the module directory
+var pathContext = &hcl.EvalContext{
+	Variables: map[string]cty.Value{
+		"path": cty.ObjectVal(map[string]cty.Value{
+			"module": cty.StringVal("."),
+			"root":   cty.StringVal("."),
+			"cwd":    cty.StringVal("."),
+		}),
+	},
+}
+
+// Issues returns diagnostics for configuration that may prevent the module from running in Terraform Cloud
+func (w *Writer) Issues() hcl.Diagnostics {
+	files, _, diags := w.parser.ConfigDirFiles(w.Dir())
+
+	for _, path := range files {
+		body, bDiags := w.parser.LoadHCLFile(path)
+		diags = append(diags, bDiags...)
+
+		syntaxBody, ok := body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		diags = append(diags, hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch node := node.(type) {
+			case *hclsyntax.ScopeTraversalExpr:
+				return terraformWorkspaceIssue(node)
+			case *hclsyntax.FunctionCallExpr:
+				return fileFunctionIssue(node)
+			case *hclsyntax.Block:
+				return localExecIssue(node)
+			}
+			return nil
+		})...)
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Subject, diags[j].Subject
+		if a == nil || b == nil {
+			return b != nil
+		}
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Byte < b.Start.Byte
+	})
+
+	return diags
+}
+
+func terraformWorkspaceIssue(expr *hclsyntax.ScopeTraversalExpr) hcl.Diagnostics {
+	if !isTerraformWorkspace(expr.Traversal) {
+		return nil
+	}
+
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "terraform.workspace is used",
+			Detail:   "terraform.workspace is always \"default\" in Terraform Cloud. Replace it with a variable.",
+			Subject:  expr.SrcRange.Ptr(),
+		},
+	}
+}
+
+// isTerraformWorkspace returns true if a traversal refers to terraform.workspace
+func isTerraformWorkspace(traversal hcl.Traversal) bool {
+	if len(traversal) < 2 || traversal.RootName() != "terraform" {
+		return false
+	}
+
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	return ok && attr.Name == "workspace"
+}
+
+func fileFunctionIssue(call *hclsyntax.FunctionCallExpr) hcl.Diagnostics {
+	if !fileFunctions[call.Name] || len(call.Args) == 0 {
+		return nil
+	}
+
+	value, diags := call.Args[0].Value(pathContext)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return nil
+	}
+
+	path := value.AsString()
+	if !pathOutsideModule(path) {
+		return nil
+	}
+
+	return hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "File read from outside the module",
+			Detail:   fmt.Sprintf(`%s() reads "%s", which is outside the module directory. Terraform Cloud only has access to files in the workspace's repository or uploaded configuration.`, call.Name, path),
+			Subject:  call.Args[0].Range().Ptr(),
+		},
+	}
+}
+
+func pathOutsideModule(path string) bool {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+		return true
+	}
+
+	clean := filepath.ToSlash(filepath.Clean(path))
+	return clean == ".." || strings.HasPrefix(clean, "../")
+}
+
+func localExecIssue(block *hclsyntax.Block) hcl.Diagnostics {
+	if block.Type != "provisioner" || len(block.Labels) == 0 || block.Labels[0] != "local-exec" {
+		return nil
+	}
+
+	for _, name := range []string{"command", "working_dir"} {
+		attr, ok := block.Body.Attributes[name]
+		if !ok || !referencesLocalFiles(attr.Expr) {
+			continue
+		}
+
+		return hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "local-exec provisioner uses local files",
+				Detail:   fmt.Sprintf(`The "%s" of this local-exec provisioner refers to paths outside the module. Terraform Cloud runs provisioners on its own workers, where only files from the configuration are available.`, name),
+				Subject:  attr.Expr.Range().Ptr(),
+			},
+		}
+	}
+
+	return nil
+}
+
+// referencesLocalFiles returns true if an expression refers to paths outside the module, such as
+// absolute paths, the home directory, or parent directories
+func referencesLocalFiles(expr hclsyntax.Expression) bool {
+	if value, diags := expr.Value(pathContext); !diags.HasErrors() && value.IsKnown() && value.Type() == cty.String {
+		return containsOutsidePath(value.AsString())
+	}
+
+	found := false
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		lit, ok := node.(*hclsyntax.LiteralValueExpr)
+		if ok && lit.Val.Type() == cty.String && containsOutsidePath(lit.Val.AsString()) {
+			found = true
+		}
+		return nil
+	})
+
+	return found
+}
+
+func containsOutsidePath(s string) bool {
+	for _, field := range strings.Fields(s) {
+		if strings.ContainsAny(field, "/~") && pathOutsideModule(field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package configwrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterIssues(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf": `
+			locals {
+				workspace = terraform.workspace
+				inside    = file("${path.module}/files/inside.txt")
+				outside   = file("${path.module}/../outside.txt")
+				absolute  = templatefile("/etc/template.tpl", {})
+				unknown   = file(var.path)
+			}
+
+			resource "null_resource" "inside" {
+				provisioner "local-exec" {
+					command = "${path.module}/scripts/run.sh"
+				}
+			}
+
+			resource "null_resource" "outside" {
+				provisioner "local-exec" {
+					command = "cat ~/.aws/credentials"
+				}
+			}
+
+			variable "path" {}
+		`,
+	})
+
+	type issue struct {
+		Summary string
+		Line    int
+	}
+
+	issues := make([]issue, 0)
+	for _, diag := range writer.Issues() {
+		issues = append(issues, issue{diag.Summary, diag.Subject.Start.Line})
+	}
+
+	assert.Equal(t, []issue{
+		{"terraform.workspace is used", 2},
+		{"File read from outside the module", 4},
+		{"File read from outside the module", 5},
+		{"local-exec provisioner uses local files", 17},
+	}, issues)
+}
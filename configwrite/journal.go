@@ -0,0 +1,168 @@
+package configwrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalDir is the directory within a module where journals are stored
+const JournalDir = ".terraform-cloud-migrate"
+
+const journalExt = ".json"
+
+// Journal records the state of every file affected by a set of changes so they can be rolled back
+type Journal struct {
+	Created time.Time     `json:"created"`
+	Files   []JournalFile `json:"files"`
+}
+
+// JournalFile is the state of a single file before changes were written
+type JournalFile struct {
+	Path    string      `json:"path"`
+	Exists  bool        `json:"exists"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Content []byte      `json:"content,omitempty"`
+}
+
+// NewJournal records the current contents of every path that the changes will write, rename, create, or delete
+func NewJournal(changes Changes) (*Journal, error) {
+	journal := &Journal{Created: time.Now().UTC()}
+	seen := make(map[string]bool)
+
+	for _, path := range changes.Paths() {
+		paths := []string{path}
+		if change := changes[path]; !change.Delete {
+			paths = append(paths, change.Destination(path))
+		}
+
+		for _, p := range paths {
+			abs, err := filepath.Abs(p)
+			if err != nil {
+				return nil, err
+			}
+
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+
+			file, err := newJournalFile(abs)
+			if err != nil {
+				return nil, err
+			}
+			journal.Files = append(journal.Files, file)
+		}
+	}
+
+	return journal, nil
+}
+
+func newJournalFile(path string) (JournalFile, error) {
+	file := JournalFile{Path: path}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+
+	file.Exists = true
+	file.Mode = info.Mode().Perm()
+	file.Content = content
+
+	return file, nil
+}
+
+// journalGitignore ignores every file in the journal directory so that journals are not committed with the migration
+const journalGitignore = "*\n"
+
+// Save writes the journal into the journal directory of the module at dir and returns its path. The directory is
+// only readable by the current user and is ignored by git.
+func (j *Journal) Save(dir string) (string, error) {
+	jdir := filepath.Join(dir, JournalDir)
+	if err := os.MkdirAll(jdir, 0700); err != nil {
+		return "", err
+	}
+	// MkdirAll does not change the mode of a directory that already exists
+	if err := os.Chmod(jdir, 0700); err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(jdir, ".gitignore"), []byte(journalGitignore), 0600); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(jdir, j.Created.Format("20060102T150405.000000000Z")+journalExt)
+	return path, ioutil.WriteFile(path, b, 0600)
+}
+
+// Rollback restores every file to the state recorded in the journal, removing files that did not exist
+func (j *Journal) Rollback() error {
+	for _, file := range j.Files {
+		if !file.Exists {
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		if err := ioutil.WriteFile(file.Path, file.Content, file.Mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LatestJournal reads the most recent journal saved for the module at dir and returns it with its path
+func LatestJournal(dir string) (*Journal, string, error) {
+	jdir := filepath.Join(dir, JournalDir)
+	infos, err := ioutil.ReadDir(jdir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), journalExt) {
+			names = append(names, info.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, "", fmt.Errorf("no journal found in %s", jdir)
+	}
+
+	sort.Strings(names)
+	path := filepath.Join(jdir, names[len(names)-1])
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var journal Journal
+	if err := json.Unmarshal(b, &journal); err != nil {
+		return nil, "", fmt.Errorf("failed to read journal %s: %v", path, err)
+	}
+
+	return &journal, path, nil
+}
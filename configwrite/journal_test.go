@@ -0,0 +1,92 @@
+package configwrite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	files := map[string]string{
+		"main.tf":             "a = 1\n",
+		"terraform.tfvars":    "foo = \"bar\"\n",
+		"secrets.auto.tfvars": "password = \"hunter2\"\n",
+	}
+	dir := testTempDir(t, files)
+	defer os.RemoveAll(dir)
+
+	changes := Changes{
+		filepath.Join(dir, "main.tf"):             &Change{File: testParseFile(t, "a = 2\n")},
+		filepath.Join(dir, "terraform.tfvars"):    &Change{File: testParseFile(t, "foo = \"bar\"\n"), Rename: "terraform.auto.tfvars"},
+		filepath.Join(dir, "variables.tf"):        &Change{File: testParseFile(t, "variable \"environment\" {}\n")},
+		filepath.Join(dir, "secrets.auto.tfvars"): &Change{File: testParseFile(t, "password = \"hunter2\"\n"), Delete: true},
+	}
+
+	journal, err := NewJournal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := journal.Save(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	gitignore, err := ioutil.ReadFile(filepath.Join(dir, JournalDir, ".gitignore"))
+	assert.NoError(t, err)
+	assert.Equal(t, "*\n", string(gitignore))
+
+	if err := changes.WriteFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, latest, err := LatestJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, path, latest)
+
+	if err := saved.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, files, testReadDir(t, dir))
+}
+
+func TestJournalSave_existingDir(t *testing.T) {
+	dir := testTempDir(t, nil)
+	defer os.RemoveAll(dir)
+
+	jdir := filepath.Join(dir, JournalDir)
+	if err := os.Mkdir(jdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := &Journal{}
+	if _, err := journal.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(jdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestLatestJournal_missing(t *testing.T) {
+	dir := testTempDir(t, nil)
+	defer os.RemoveAll(dir)
+
+	_, _, err := LatestJournal(dir)
+	assert.Error(t, err)
+}
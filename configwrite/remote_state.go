@@ -1,6 +1,8 @@
 package configwrite
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 
 	"github.com/hashicorp/hcl/v2"
@@ -11,6 +13,9 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
+// defaultWorkspace is the workspace that every backend has
+const defaultWorkspace = "default"
+
 type RemoteState struct {
 	writer        *Writer
 	Path          string
@@ -31,9 +36,15 @@ func (s *RemoteState) Description() string {
 	return `A "remote" backend should be configured for Terraform Cloud (https://www.terraform.io/docs/backends/types/remote.html)`
 }
 
-// Changes updates the configured backend
-func (s *RemoteState) Changes() (Changes, hcl.Diagnostics) {
-	changes := Changes{}
+// Complete checks if any terraform_remote_state data sources still refer to the module's backend
+func (s *RemoteState) Complete() bool {
+	sources, _ := s.dependentSources()
+	return len(sources) == 0
+}
+
+// dependentSources finds the terraform_remote_state data sources in Path that refer to the module's backend
+func (s *RemoteState) dependentSources() ([]*configs.Resource, hcl.Diagnostics) {
+	sources := make([]*configs.Resource, 0)
 	var diags hcl.Diagnostics
 
 	_ = afero.Walk(s.writer.fs, s.Path, func(path string, info os.FileInfo, err error) error {
@@ -45,128 +56,9 @@ func (s *RemoteState) Changes() (Changes, hcl.Diagnostics) {
 			return nil
 		}
 
-		sources, sDiags := s.sources(path)
+		found, sDiags := s.sources(path)
 		diags = append(diags, sDiags...)
-
-		for _, source := range sources {
-			filepath := source.DeclRange.Filename
-			file, fDiags := s.writer.File(source.DeclRange.Filename)
-			diags = append(diags, fDiags...)
-
-			block := file.Body().FirstMatchingBlock("data", []string{
-				source.Type,
-				source.Name,
-			})
-
-			workspace := block.Body().RemoveAttribute("workspace")
-
-			block.Body().SetAttributeValue("backend", cty.StringVal("remote"))
-			block.Body().SetAttributeRaw("config", flattenTokens([]hclwrite.Tokens{
-				{
-					{
-						Type:  hclsyntax.TokenOBrace,
-						Bytes: []byte("{"),
-					},
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n"),
-					},
-					{
-						Type:  hclsyntax.TokenIdent,
-						Bytes: []byte("hostname"),
-					},
-					{
-						Type:  hclsyntax.TokenEqual,
-						Bytes: []byte("="),
-					},
-					{
-						Type:  hclsyntax.TokenOQuote,
-						Bytes: []byte(`"`),
-					},
-					{
-						Type:  hclsyntax.TokenQuotedLit,
-						Bytes: []byte(s.RemoteBackend.Hostname),
-					},
-					{
-						Type:  hclsyntax.TokenCQuote,
-						Bytes: []byte(`"`),
-					},
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n"),
-					},
-					{
-						Type:  hclsyntax.TokenIdent,
-						Bytes: []byte("organization"),
-					},
-					{
-						Type:  hclsyntax.TokenEqual,
-						Bytes: []byte("="),
-					},
-					{
-						Type:  hclsyntax.TokenOQuote,
-						Bytes: []byte(`"`),
-					},
-					{
-						Type:  hclsyntax.TokenQuotedLit,
-						Bytes: []byte(s.RemoteBackend.Organization),
-					},
-					{
-						Type:  hclsyntax.TokenCQuote,
-						Bytes: []byte(`"`),
-					},
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n\n"),
-					},
-					{
-						Type:  hclsyntax.TokenIdent,
-						Bytes: []byte("workspaces"),
-					},
-					{
-						Type:  hclsyntax.TokenEqual,
-						Bytes: []byte("="),
-					},
-					{
-						Type:  hclsyntax.TokenOBrace,
-						Bytes: []byte("{"),
-					},
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n"),
-					},
-					{
-						Type:  hclsyntax.TokenStringLit,
-						Bytes: []byte("name"),
-					},
-					{
-						Type:  hclsyntax.TokenEqual,
-						Bytes: []byte("="),
-					},
-				},
-				s.workspaceNameTokens(workspace),
-				{
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n"),
-					},
-					{
-						Type:  hclsyntax.TokenCBrace,
-						Bytes: []byte("}"),
-					},
-					{
-						Type:  hclsyntax.TokenNewline,
-						Bytes: []byte("\n"),
-					},
-					{
-						Type:  hclsyntax.TokenCBrace,
-						Bytes: []byte("}"),
-					},
-				},
-			}))
-
-			changes[filepath] = &Change{File: file}
-		}
+		sources = append(sources, found...)
 
 		if diags.HasErrors() {
 			return diags
@@ -175,6 +67,44 @@ func (s *RemoteState) Changes() (Changes, hcl.Diagnostics) {
 		return nil
 	})
 
+	return sources, diags
+}
+
+// Changes updates the configured backend
+func (s *RemoteState) Changes() (Changes, hcl.Diagnostics) {
+	changes := Changes{}
+	sources, diags := s.dependentSources()
+	if diags.HasErrors() {
+		return changes, diags
+	}
+
+	for _, source := range sources {
+		filepath := source.DeclRange.Filename
+		file, fDiags := s.writer.File(source.DeclRange.Filename)
+		diags = append(diags, fDiags...)
+
+		block := file.Body().FirstMatchingBlock("data", []string{
+			source.Type,
+			source.Name,
+		})
+
+		workspace := block.Body().GetAttribute("workspace")
+		name, nDiags := s.workspaceNameTokens(source, workspace)
+		diags = append(diags, nDiags...)
+		if name == nil {
+			continue
+		}
+
+		comments := commentTokens(block.Body().GetAttribute("config").Expr().BuildTokens(nil))
+
+		block.Body().RemoveAttribute("workspace")
+
+		block.Body().SetAttributeValue("backend", cty.StringVal("remote"))
+		block.Body().SetAttributeRaw("config", s.configTokens(name, comments))
+
+		changes[filepath] = &Change{File: file}
+	}
+
 	return changes, diags
 }
 
@@ -183,105 +113,207 @@ func (s *RemoteState) sources(path string) ([]*configs.Resource, hcl.Diagnostics
 	writer, diags := newWriter(path, s.writer.fs)
 	sources := make([]*configs.Resource, 0)
 
-Source:
+	if !s.writer.HasBackend() {
+		return sources, diags
+	}
+
+	ctx, eDiags := writer.EvalContext()
+	diags = append(diags, eDiags...)
+
+	// count.index and each are known only for each instance of a data source
+	ctx = ctx.NewChild()
+	ctx.Variables = map[string]cty.Value{
+		"count": cty.UnknownVal(cty.Object(map[string]cty.Type{"index": cty.Number})),
+		"each": cty.UnknownVal(cty.Object(map[string]cty.Type{
+			"key":   cty.String,
+			"value": cty.DynamicPseudoType,
+		})),
+	}
+
+	backendType := s.writer.Backend().Type
+	backendConfig := s.writer.backendStateConfig()
+
 	for _, source := range writer.RemoteStateDataSources() {
 		attrs, aDiags := source.Config.JustAttributes()
 		diags = append(diags, aDiags...)
 
-		backend, bDiags := attrs["backend"].Expr.Value(nil)
-		diags = append(diags, bDiags...)
-
-		if backend.AsString() != s.writer.Backend().Type {
+		if attrs["backend"] == nil || attrs["config"] == nil {
 			continue
 		}
 
-		config, cDiags := attrs["config"].Expr.Value(nil)
-		// errors on interpolations
-		if cDiags.HasErrors() {
+		backend, ok := resolveRemoteStateAttr(attrs["backend"], ctx)
+		if !ok || !backend.IsWhollyKnown() {
+			diags = append(diags, unresolvedRemoteState(source, attrs["backend"]))
 			continue
 		}
-		diags = append(diags, cDiags...)
 
-		remoteBackendConfigAttrs, rDiags := s.writer.Backend().Config.JustAttributes()
-		// errors when workspaces is block
-		if rDiags.HasErrors() {
+		if backend.Type() != cty.String || backend.AsString() != backendType {
 			continue
 		}
-		diags = append(diags, rDiags...)
 
-		for key, value := range config.AsValueMap() {
-			// workspaces is a block
-			if key == "workspaces" {
-				continue Source
-			}
-
-			rbValue, rDiags := remoteBackendConfigAttrs[key].Expr.Value(nil)
-			diags = append(diags, rDiags...)
+		config, ok := resolveRemoteStateAttr(attrs["config"], ctx)
+		if !ok || !config.IsKnown() {
+			diags = append(diags, unresolvedRemoteState(source, attrs["config"]))
+			continue
+		}
 
-			if value.AsString() != rbValue.AsString() {
-				continue Source
-			}
+		sourceConfig, ok := sourceStateConfig(path, config)
+		if !ok {
+			continue
 		}
 
-		sources = append(sources, source)
+		switch matchState(backendType, backendConfig, sourceConfig) {
+		case stateMatched:
+			sources = append(sources, source)
+		case stateUnknown:
+			diags = append(diags, unresolvedRemoteState(source, attrs["config"]))
+		}
 	}
 
 	return sources, diags
 }
 
-func (s *RemoteState) workspaceNameTokens(workspace *hclwrite.Attribute) hclwrite.Tokens {
-	if s.RemoteBackend.Workspaces.Prefix == "" {
-		return hclwrite.Tokens{
-			{
-				Type:  hclsyntax.TokenOQuote,
-				Bytes: []byte(`"`),
-			},
-			{
-				Type:  hclsyntax.TokenStringLit,
-				Bytes: []byte(s.RemoteBackend.Workspaces.Name),
-			},
-			{
-				Type:  hclsyntax.TokenCQuote,
-				Bytes: []byte(`"`),
+// resolveRemoteStateAttr evaluates an argument of a remote state data source, returning false if it cannot be evaluated.
+// Values may contain unknown values, such as those that depend on count or for_each.
+func resolveRemoteStateAttr(attr *hcl.Attribute, ctx *hcl.EvalContext) (cty.Value, bool) {
+	value, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() || value.IsNull() {
+		return cty.NilVal, false
+	}
+
+	return value, true
+}
+
+func unresolvedRemoteState(source *configs.Resource, attr *hcl.Attribute) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Remote state not resolved",
+		Detail:   fmt.Sprintf(`The "%s" argument of data "%s" "%s" depends on values that are not known without running Terraform, so it could not be compared to the module's backend. Update it manually if it reads this module's state.`, attr.Name, source.Type, source.Name),
+		Subject:  attr.Expr.Range().Ptr(),
+	}
+}
+
+// workspaceNameTokens returns the expression for the workspace name in the new data source, or nil if it cannot be determined.
+// Literal workspace names are renamed with the workspace map.
+func (s *RemoteState) workspaceNameTokens(source *configs.Resource, workspace *hclwrite.Attribute) (hclwrite.Tokens, hcl.Diagnostics) {
+	workspaces := s.RemoteBackend.Workspaces
+	if workspaces.Name != "" {
+		return hclwrite.TokensForValue(cty.StringVal(workspaces.Name)), nil
+	}
+
+	if workspace == nil {
+		// data sources without a workspace read the default workspace, which only exists in Terraform Cloud if it is mapped
+		if name, ok := workspaces.Map[defaultWorkspace]; ok {
+			return hclwrite.TokensForValue(cty.StringVal(name)), nil
+		}
+
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Remote state workspace unknown",
+				Detail:   fmt.Sprintf(`data "%s" "%s" reads the "%s" workspace, which has no corresponding Terraform Cloud workspace. Add it to the workspace map or update the data source manually.`, source.Type, source.Name, defaultWorkspace),
+				Subject:  &source.DeclRange,
 			},
 		}
 	}
 
-	return flattenTokens([]hclwrite.Tokens{
-		{
-			{
-				Type:  hclsyntax.TokenOQuote,
-				Bytes: []byte(`"`),
-			},
-			{
-				Type:  hclsyntax.TokenStringLit,
-				Bytes: []byte(s.RemoteBackend.Workspaces.Prefix),
-			},
-			{
-				Type:  hclsyntax.TokenTemplateInterp,
-				Bytes: []byte("${"),
-			},
-		},
-		workspace.Expr().BuildTokens(nil),
-		{
-			{
-				Type:  hclsyntax.TokenTemplateSeqEnd,
-				Bytes: []byte("}"),
-			},
-			{
-				Type:  hclsyntax.TokenCQuote,
-				Bytes: []byte(`"`),
-			},
-		},
+	if name, ok := literalWorkspace(source); ok {
+		return hclwrite.TokensForValue(cty.StringVal(workspaces.RemoteName(name))), nil
+	}
+
+	var diags hcl.Diagnostics
+	if len(workspaces.Map) != 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Remote state workspace not mapped",
+			Detail:   fmt.Sprintf(`The workspace of data "%s" "%s" is not a literal string, so the workspace map cannot be applied to it. Check that the generated workspace name is correct.`, source.Type, source.Name),
+			Subject:  &source.DeclRange,
+		})
+	}
+
+	if len(workspaces.Tags) != 0 {
+		// tagged workspaces keep their local names
+		return workspace.Expr().BuildTokens(nil), diags
+	}
+
+	name, pDiags := prefixTokens(workspaces.Prefix, workspace.Expr().BuildTokens(nil))
+	return name, append(diags, pDiags...)
+}
+
+// literalWorkspace returns the workspace of a data source if it is a literal string
+func literalWorkspace(source *configs.Resource) (string, bool) {
+	attrs, diags := source.Config.JustAttributes()
+	if diags.HasErrors() || attrs["workspace"] == nil {
+		return "", false
+	}
+
+	value, diags := attrs["workspace"].Expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+
+	return value.AsString(), true
+}
+
+// configTokens returns the config object for a data source that reads the named Terraform Cloud workspace. Comments
+// from the original config object are kept at the top.
+func (s *RemoteState) configTokens(name hclwrite.Tokens, comments hclwrite.Tokens) hclwrite.Tokens {
+	return objectTokens(func(body *hclwrite.Body) {
+		if len(comments) > 0 {
+			body.AppendUnstructuredTokens(comments)
+		}
+
+		body.SetAttributeValue("hostname", cty.StringVal(s.RemoteBackend.Hostname))
+		body.SetAttributeValue("organization", cty.StringVal(s.RemoteBackend.Organization))
+		body.AppendNewline()
+
+		body.SetAttributeRaw("workspaces", objectTokens(func(body *hclwrite.Body) {
+			body.SetAttributeRaw("name", name)
+		}))
 	})
 }
 
-func flattenTokens(in []hclwrite.Tokens) hclwrite.Tokens {
-	out := make(hclwrite.Tokens, 0)
-	for _, tokens := range in {
-		out = append(out, tokens...)
+// objectTokens returns a multi-line object expression with the contents of a body. The body is generated as a
+// block, whose braces and newlines are the same as an object's.
+func objectTokens(build func(body *hclwrite.Body)) hclwrite.Tokens {
+	block := hclwrite.NewBlock("object", nil)
+	build(block.Body())
+
+	// the block's type name and trailing newline are not part of the expression
+	tokens := block.BuildTokens(nil)
+	return tokens[1 : len(tokens)-1]
+}
+
+// commentTokens returns the comments in an expression, each ending in a newline
+func commentTokens(expr hclwrite.Tokens) hclwrite.Tokens {
+	var comments hclwrite.Tokens
+	for _, token := range expr {
+		if token.Type != hclsyntax.TokenComment {
+			continue
+		}
+
+		comment := *token
+		comment.SpacesBefore = 0
+		if !bytes.HasSuffix(comment.Bytes, []byte("\n")) {
+			comment.Bytes = append(append([]byte{}, comment.Bytes...), '\n')
+		}
+		comments = append(comments, &comment)
+	}
+	return comments
+}
+
+// prefixTokens returns a string template that interpolates an expression after a literal prefix. The template is
+// parsed so that its tokens are generated by hclwrite.
+func prefixTokens(prefix string, expr hclwrite.Tokens) (hclwrite.Tokens, hcl.Diagnostics) {
+	// the quoted prefix is escaped for use in a template
+	quoted := hclwrite.TokensForValue(cty.StringVal(prefix)).Bytes()
+	src := fmt.Sprintf("template = %s${%s}\"\n", quoted[:len(quoted)-1], expr.Bytes())
+
+	file, diags := hclwrite.ParseConfig([]byte(src), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
 	}
-	return out
+	return file.Body().GetAttribute("template").Expr().BuildTokens(nil), nil
 }
 
 var _ Step = (*RemoteState)(nil)
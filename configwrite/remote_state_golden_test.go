@@ -0,0 +1,88 @@
+package configwrite
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestRemoteState_golden updates each testdata/remote_state/*.tf file as a dependent module and compares it to
+// the .golden file with the same name
+func TestRemoteState_golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "remote_state", "*.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		input := input
+		name := strings.TrimSuffix(filepath.Base(input), ".tf")
+
+		t.Run(name, func(t *testing.T) {
+			in, err := ioutil.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writer := newTestModule(t, map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							bucket = "terraform-state"
+							key    = "app/terraform.tfstate"
+							region = "us-east-1"
+						}
+					}
+				`,
+				"dependent/main.tf": string(in),
+			})
+
+			step := &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "app.terraform.io",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Prefix: "app-",
+						Map: map[string]string{
+							"stg": "app-staging",
+						},
+					},
+				},
+				Path: "dependent/",
+			}
+
+			// warnings about unmapped workspaces are expected
+			changes, diags := step.WithWriter(writer).Changes()
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			change, ok := changes["dependent/main.tf"]
+			if !assert.True(t, ok) {
+				return
+			}
+
+			out := change.File.Bytes()
+			assert.Equal(t, string(hclwrite.Format(out)), string(out), "output is not formatted")
+
+			golden := strings.TrimSuffix(input, ".tf") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, out, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, string(expected), string(out))
+		})
+	}
+}
@@ -2,6 +2,8 @@ package configwrite
 
 import (
 	"testing"
+
+	"github.com/hashicorp/hcl/v2"
 )
 
 func TestRemoteState(t *testing.T) {
@@ -173,5 +175,402 @@ func TestRemoteState(t *testing.T) {
 				`,
 			},
 		},
+		{
+			name: "cloud/tags",
+			step: &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Style:        BackendStyleCloud,
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Tags: []string{"app"},
+					},
+				},
+				Path: "dependent/",
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+				"./dependent/a/backend.tf": `
+					data "terraform_remote_state" "match" {
+						backend   = "s3"
+						workspace = "production"
+					
+						config = {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"dependent/a/backend.tf": `
+					data "terraform_remote_state" "match" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "production"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "incomplete/prefix_map",
+			step: &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Prefix: "app-",
+						Map: map[string]string{
+							"default": "app-default",
+							"stg":     "app-staging",
+							"prod":    "app-production",
+						},
+					},
+				},
+				Path: "dependent/",
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+				"./dependent/a/backend.tf": `
+					data "terraform_remote_state" "mapped" {
+						backend   = "s3"
+						workspace = "stg"
+					
+						config = {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+					
+					data "terraform_remote_state" "unmapped" {
+						backend   = "s3"
+						workspace = "dev"
+					
+						config = {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+					
+					data "terraform_remote_state" "default" {
+						backend = "s3"
+					
+						config = {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"dependent/a/backend.tf": `
+					data "terraform_remote_state" "mapped" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "app-staging"
+							}
+						}
+					}
+					
+					data "terraform_remote_state" "unmapped" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "app-dev"
+							}
+						}
+					}
+					
+					data "terraform_remote_state" "default" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "app-default"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "incomplete/prefix_default",
+			step: &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Prefix: "app-",
+					},
+				},
+				Path: "dependent/",
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+				"./dependent/a/backend.tf": `
+					data "terraform_remote_state" "default" {
+						backend = "s3"
+					
+						config = {
+							key    = "terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				{
+					Severity: hcl.DiagWarning,
+					Summary:  "Remote state workspace unknown",
+					Detail:   `data "terraform_remote_state" "default" reads the "default" workspace, which has no corresponding Terraform Cloud workspace. Add it to the workspace map or update the data source manually.`,
+					Subject: &hcl.Range{
+						Filename: "dependent/a/backend.tf",
+						Start:    hcl.Pos{Line: 1, Column: 1, Byte: 0},
+						End:      hcl.Pos{Line: 1, Column: 40, Byte: 39},
+					},
+				},
+			},
+		},
+		{
+			name: "incomplete/interpolated",
+			step: &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Name: "ws",
+					},
+				},
+				Path: "dependent/",
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key    = "app/terraform.tfstate"
+							bucket = "terraform-state"
+							region = "us-east-1"
+						}
+					}
+				`,
+				"./dependent/a/variables.tf": `
+					variable "state_bucket" {
+						default = "terraform-state"
+					}
+
+					variable "region" {}
+
+					variable "unknown" {}
+
+					locals {
+						app    = "app"
+						key    = "${local.app}/terraform.tfstate"
+					}
+				`,
+				"./dependent/a/terraform.tfvars": `
+					region = "us-east-1"
+				`,
+				"./dependent/a/backend.tf": `
+					data "terraform_remote_state" "match" {
+						backend = "s3"
+					
+						config = {
+							key    = local.key
+							bucket = var.state_bucket
+							region = lower(var.region)
+						}
+					}
+					
+					data "terraform_remote_state" "unresolved" {
+						backend = "s3"
+					
+						config = {
+							key    = var.unknown
+							bucket = var.state_bucket
+							region = var.region
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"dependent/a/backend.tf": `
+					data "terraform_remote_state" "match" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "ws"
+							}
+						}
+					}
+					
+					data "terraform_remote_state" "unresolved" {
+						backend = "s3"
+					
+						config = {
+							key    = var.unknown
+							bucket = var.state_bucket
+							region = var.region
+						}
+					}
+				`,
+			},
+			diags: hcl.Diagnostics{
+				{
+					Severity: hcl.DiagWarning,
+					Summary:  "Remote state not resolved",
+					Detail:   `The "config" argument of data "terraform_remote_state" "unresolved" depends on values that are not known without running Terraform, so it could not be compared to the module's backend. Update it manually if it reads this module's state.`,
+					Subject: &hcl.Range{
+						Filename: "dependent/a/backend.tf",
+						Start:    hcl.Pos{Line: 14, Column: 12, Byte: 236},
+						End:      hcl.Pos{Line: 18, Column: 4, Byte: 320},
+					},
+				},
+			},
+		},
+		{
+			name: "incomplete/identity",
+			step: &RemoteState{
+				RemoteBackend: RemoteBackendConfig{
+					Hostname:     "host.name",
+					Organization: "org",
+					Workspaces: WorkspaceConfig{
+						Name: "ws",
+					},
+				},
+				Path: "dependent/",
+			},
+			in: map[string]string{
+				"backend.tf": `
+					terraform {
+						backend "s3" {
+							key     = "terraform.tfstate"
+							bucket  = "terraform-state"
+							region  = "us-east-1"
+							encrypt = true
+						}
+					}
+				`,
+				"./dependent/a/backend.tf": `
+					data "terraform_remote_state" "other_credentials" {
+						backend = "s3"
+					
+						config = {
+							key      = "terraform.tfstate"
+							bucket   = "terraform-state"
+							region   = "us-west-2"
+							profile  = "readonly"
+							role_arn = "arn:aws:iam::123456789012:role/state"
+						}
+					}
+					
+					data "terraform_remote_state" "other_prefix" {
+						backend = "s3"
+					
+						config = {
+							key                  = "terraform.tfstate"
+							bucket               = "terraform-state"
+							workspace_key_prefix = "workspaces"
+						}
+					}
+					
+					data "terraform_remote_state" "not_string" {
+						backend = "s3"
+					
+						config = {
+							key    = ["terraform.tfstate"]
+							bucket = "terraform-state"
+						}
+					}
+				`,
+			},
+			expected: map[string]string{
+				"dependent/a/backend.tf": `
+					data "terraform_remote_state" "other_credentials" {
+						backend = "remote"
+					
+						config = {
+							hostname     = "host.name"
+							organization = "org"
+					
+							workspaces = {
+								name = "ws"
+							}
+						}
+					}
+					
+					data "terraform_remote_state" "other_prefix" {
+						backend = "s3"
+					
+						config = {
+							key                  = "terraform.tfstate"
+							bucket               = "terraform-state"
+							workspace_key_prefix = "workspaces"
+						}
+					}
+					
+					data "terraform_remote_state" "not_string" {
+						backend = "s3"
+					
+						config = {
+							key    = ["terraform.tfstate"]
+							bucket = "terraform-state"
+						}
+					}
+				`,
+			},
+		},
 	})
 }
@@ -0,0 +1,190 @@
+package configwrite
+
+import (
+	"path/filepath"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// stateConfig is the config of a backend or terraform_remote_state data source along with the directory of its module
+type stateConfig struct {
+	Dir    string
+	Values map[string]cty.Value
+}
+
+// stateMatch is the result of comparing a terraform_remote_state data source to a backend
+type stateMatch int
+
+const (
+	stateMismatch stateMatch = iota
+	stateMatched
+	// stateUnknown means the source depends on values that are not known without running Terraform
+	stateUnknown
+)
+
+// stateMatcher compares a terraform_remote_state data source to the state stored by a backend
+type stateMatcher func(backend, source stateConfig) stateMatch
+
+// stateMatchers match backends by the config keys that identify their state. Other keys, such as
+// credentials and regions, can differ between modules that read the same state.
+var stateMatchers = map[string]stateMatcher{
+	"s3": identityMatcher(map[string]string{
+		"bucket":               "",
+		"key":                  "",
+		"workspace_key_prefix": "env:",
+	}),
+	"gcs": identityMatcher(map[string]string{
+		"bucket": "",
+		"prefix": "",
+	}),
+	"azurerm": identityMatcher(map[string]string{
+		"storage_account_name": "",
+		"container_name":       "",
+		"key":                  "",
+	}),
+	"consul": identityMatcher(map[string]string{
+		"address": "",
+		"path":    "",
+	}),
+	"local": matchLocal,
+}
+
+// matchState compares source to the state of a backend of the given type
+func matchState(backendType string, backend, source stateConfig) stateMatch {
+	if match, ok := stateMatchers[backendType]; ok {
+		return match(backend, source)
+	}
+
+	return matchAllKeys(backend, source)
+}
+
+// identityMatcher matches configs with equal values for each key, using defaults for unset keys
+func identityMatcher(defaults map[string]string) stateMatcher {
+	return func(backend, source stateConfig) stateMatch {
+		result := stateMatched
+		for key, def := range defaults {
+			if !knownValue(source.Values, key) {
+				result = stateUnknown
+				continue
+			}
+
+			a, ok := configString(backend.Values, key, def)
+			if !ok {
+				return stateMismatch
+			}
+
+			b, ok := configString(source.Values, key, def)
+			if !ok || a != b {
+				return stateMismatch
+			}
+		}
+
+		return result
+	}
+}
+
+// matchLocal matches local state paths, which are relative to each module's directory
+func matchLocal(backend, source stateConfig) stateMatch {
+	if !knownValue(source.Values, "path") {
+		return stateUnknown
+	}
+
+	a, ok := configString(backend.Values, "path", "terraform.tfstate")
+	if !ok {
+		return stateMismatch
+	}
+
+	b, ok := configString(source.Values, "path", "terraform.tfstate")
+	if !ok || modulePath(backend.Dir, a) != modulePath(source.Dir, b) {
+		return stateMismatch
+	}
+
+	return stateMatched
+}
+
+// matchAllKeys is used for other backends and matches if every key set by the source has the same value in the backend
+func matchAllKeys(backend, source stateConfig) stateMatch {
+	result := stateMatched
+	for key, value := range source.Values {
+		if !value.IsWhollyKnown() {
+			result = stateUnknown
+			continue
+		}
+
+		rbValue, ok := backend.Values[key]
+		if !ok || !value.Type().Equals(rbValue.Type()) || !value.RawEquals(rbValue) {
+			return stateMismatch
+		}
+	}
+
+	return result
+}
+
+// knownValue returns false if a config value is set but not known
+func knownValue(values map[string]cty.Value, key string) bool {
+	value, ok := values[key]
+	return !ok || value.IsWhollyKnown()
+}
+
+// configString returns a config value as a string, or the default if it is not set.
+// It returns false if the value cannot be converted to a string.
+func configString(values map[string]cty.Value, key string, def string) (string, bool) {
+	value, ok := values[key]
+	if !ok || value.IsNull() {
+		return def, true
+	}
+
+	value, err := convert.Convert(value, cty.String)
+	if err != nil || !value.IsKnown() || value.IsNull() {
+		return "", false
+	}
+
+	return value.AsString(), true
+}
+
+func modulePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// backendStateConfig evaluates the config of the writer's backend, merged with partial configuration from
+// SetBackendConfig. Arguments that cannot be evaluated, such as nested blocks, are omitted.
+func (w *Writer) backendStateConfig() stateConfig {
+	config := stateConfig{
+		Dir:    w.Dir(),
+		Values: make(map[string]cty.Value),
+	}
+
+	attrs, _ := w.Backend().Config.JustAttributes()
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !value.IsWhollyKnown() {
+			continue
+		}
+
+		config.Values[name] = value
+	}
+
+	for name, value := range w.backendConfig {
+		config.Values[name] = value
+	}
+
+	return config
+}
+
+// sourceStateConfig returns the values of a resolved terraform_remote_state config, returning false if it is not an object or map
+func sourceStateConfig(dir string, value cty.Value) (stateConfig, bool) {
+	ty := value.Type()
+	if !ty.IsObjectType() && !ty.IsMapType() {
+		return stateConfig{}, false
+	}
+
+	return stateConfig{
+		Dir:    dir,
+		Values: value.AsValueMap(),
+	}, true
+}
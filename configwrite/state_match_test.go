@@ -0,0 +1,192 @@
+package configwrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMatchState(t *testing.T) {
+	tests := []struct {
+		name        string
+		backendType string
+		backend     stateConfig
+		source      stateConfig
+		expected    stateMatch
+	}{
+		{
+			name:        "gcs",
+			backendType: "gcs",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"bucket":      cty.StringVal("tf-state"),
+				"prefix":      cty.StringVal("app"),
+				"credentials": cty.StringVal("admin.json"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("tf-state"),
+				"prefix": cty.StringVal("app"),
+			}},
+			expected: stateMatched,
+		},
+		{
+			name:        "gcs/prefix",
+			backendType: "gcs",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("tf-state"),
+				"prefix": cty.StringVal("app"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("tf-state"),
+			}},
+			expected: stateMismatch,
+		},
+		{
+			name:        "azurerm",
+			backendType: "azurerm",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"resource_group_name":  cty.StringVal("state"),
+				"storage_account_name": cty.StringVal("tfstate"),
+				"container_name":       cty.StringVal("tfstate"),
+				"key":                  cty.StringVal("app.tfstate"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"storage_account_name": cty.StringVal("tfstate"),
+				"container_name":       cty.StringVal("tfstate"),
+				"key":                  cty.StringVal("app.tfstate"),
+				"access_key":           cty.StringVal("secret"),
+			}},
+			expected: stateMatched,
+		},
+		{
+			name:        "azurerm/key",
+			backendType: "azurerm",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"storage_account_name": cty.StringVal("tfstate"),
+				"container_name":       cty.StringVal("tfstate"),
+				"key":                  cty.StringVal("app.tfstate"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"storage_account_name": cty.StringVal("tfstate"),
+				"container_name":       cty.StringVal("tfstate"),
+				"key":                  cty.StringVal("other.tfstate"),
+			}},
+			expected: stateMismatch,
+		},
+		{
+			name:        "consul",
+			backendType: "consul",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"address": cty.StringVal("consul.example.com"),
+				"path":    cty.StringVal("app"),
+				"scheme":  cty.StringVal("https"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"address":      cty.StringVal("consul.example.com"),
+				"path":         cty.StringVal("app"),
+				"access_token": cty.StringVal("token"),
+			}},
+			expected: stateMatched,
+		},
+		{
+			name:        "local",
+			backendType: "local",
+			backend: stateConfig{
+				Dir: "app",
+				Values: map[string]cty.Value{
+					"path": cty.StringVal("state/terraform.tfstate"),
+				},
+			},
+			source: stateConfig{
+				Dir: "consumer",
+				Values: map[string]cty.Value{
+					"path": cty.StringVal("../app/state/terraform.tfstate"),
+				},
+			},
+			expected: stateMatched,
+		},
+		{
+			name:        "local/default",
+			backendType: "local",
+			backend:     stateConfig{Dir: "app"},
+			source: stateConfig{
+				Dir: "consumer",
+				Values: map[string]cty.Value{
+					"path": cty.StringVal("terraform.tfstate"),
+				},
+			},
+			expected: stateMismatch,
+		},
+		{
+			name:        "other",
+			backendType: "http",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"address": cty.StringVal("https://state.example.com"),
+				"retry":   cty.NumberIntVal(2),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"address": cty.StringVal("https://state.example.com"),
+				"retry":   cty.NumberIntVal(2),
+			}},
+			expected: stateMatched,
+		},
+		{
+			name:        "other/mismatch",
+			backendType: "http",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"address": cty.StringVal("https://state.example.com"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"address":  cty.StringVal("https://state.example.com"),
+				"username": cty.StringVal("user"),
+			}},
+			expected: stateMismatch,
+		},
+		{
+			name:        "s3/unknown_identity",
+			backendType: "s3",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("terraform-state"),
+				"key":    cty.StringVal("terraform.tfstate"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("terraform-state"),
+				"key":    cty.UnknownVal(cty.String),
+			}},
+			expected: stateUnknown,
+		},
+		{
+			name:        "s3/unknown_region",
+			backendType: "s3",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("terraform-state"),
+				"key":    cty.StringVal("terraform.tfstate"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("terraform-state"),
+				"key":    cty.StringVal("terraform.tfstate"),
+				"region": cty.UnknownVal(cty.String),
+			}},
+			expected: stateMatched,
+		},
+		{
+			name:        "s3/unknown_mismatch",
+			backendType: "s3",
+			backend: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("terraform-state"),
+				"key":    cty.StringVal("terraform.tfstate"),
+			}},
+			source: stateConfig{Values: map[string]cty.Value{
+				"bucket": cty.StringVal("other-state"),
+				"key":    cty.UnknownVal(cty.String),
+			}},
+			expected: stateMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, matchState(test.backendType, test.backend, test.source))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package configwrite
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 )
@@ -19,6 +20,21 @@ type Step interface {
 	WithWriter(*Writer) Step
 }
 
+// Completer is implemented by steps that can check whether a module already includes their changes
+type Completer interface {
+	Complete() bool
+}
+
+// Complete checks whether a step has any remaining changes, using Complete() if the step implements Completer
+func Complete(step Step) (bool, hcl.Diagnostics) {
+	if c, ok := step.(Completer); ok {
+		return c.Complete(), nil
+	}
+
+	changes, diags := step.Changes()
+	return len(changes) == 0, diags
+}
+
 func NewSteps(w *Writer, steps Steps) Steps {
 	for _, step := range steps {
 		step.WithWriter(w)
@@ -32,21 +48,67 @@ func (s Steps) Append(steps ...Step) Steps {
 	return append(s, steps...)
 }
 
+// Changes runs each step in order and merges their changes. Each change records the steps that contributed to it along with a diff for each step.
 func (s Steps) Changes() (Changes, hcl.Diagnostics) {
 	result := make(Changes)
+	snapshots := make(map[string][]byte)
 	var diags hcl.Diagnostics
 
 	for _, step := range s {
-		changes, diags := step.Changes()
+		changes, sDiags := step.Changes()
+		diags = append(diags, sDiags...)
+
+		for _, path := range changes.Paths() {
+			change := changes[path]
+
+			before, ok := snapshots[path]
+			if !ok {
+				var err error
+				if before, err = readOriginal(path); err != nil {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "file read error",
+						Detail:   fmt.Sprintf("file %s could not be read: %v", path, err),
+					})
+					continue
+				}
+			}
 
-		for path, change := range changes {
-			if err, ok := result.Add(path, change).(*renameCollisionError); ok {
+			// step diffs show only the step's edits, so lines that differ only in formatting are unchanged
+			var after []byte
+			if !change.Delete {
+				after = keepFormatting(before, change.formatted())
+			}
+
+			diff, err := unifiedDiff(path, change.Destination(path), before, after, change.Rename != "")
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "diff error",
+					Detail:   fmt.Sprintf(`The changes from the "%s" step to %s could not be compared: %v`, step.Name(), path, err),
+				})
+				continue
+			}
+
+			change.Steps = []StepChange{{Step: step.Name(), Diff: diff}}
+
+			switch err := result.Add(path, change).(type) {
+			case *renameCollisionError:
 				diags = append(diags, &hcl.Diagnostic{
 					Severity: hcl.DiagWarning,
 					Summary:  "Rename skipped due to conflict",
 					Detail:   fmt.Sprintf(`The "%s" step attempted to rename %s to %s, but a previous step already renamed this file to %s.`, step.Name(), path, err.Proposed, err.Existing),
 					Subject:  &hcl.Range{Filename: err.Proposed},
 				})
+			case *fileConflictError:
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Conflicting changes",
+					Detail:   fmt.Sprintf(`The "%s" step changed %s, but the file was already changed by %s. The changes cannot be combined.`, step.Name(), path, strings.Join(result[path].StepNames(), ", ")),
+					Subject:  &hcl.Range{Filename: path},
+				})
+			case nil:
+				snapshots[path] = after
 			}
 		}
 
@@ -1,10 +1,14 @@
 package configwrite
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type stepTest struct {
@@ -12,6 +16,7 @@ type stepTest struct {
 	step     Step
 	in       map[string]string
 	expected map[string]string
+	deleted  []string
 	diags    hcl.Diagnostics
 }
 
@@ -24,7 +29,13 @@ func testStepChanges(t *testing.T, tests stepTests) {
 			changes, diags := test.step.WithWriter(writer).Changes()
 
 			out := make(map[string]string)
-			for path, change := range changes {
+			var deleted []string
+			for _, path := range changes.Paths() {
+				change := changes[path]
+				if change.Delete {
+					deleted = append(deleted, path)
+					continue
+				}
 				out[change.Destination(path)] = string(change.File.Bytes())
 			}
 
@@ -34,7 +45,108 @@ func testStepChanges(t *testing.T, tests stepTests) {
 			}
 
 			assert.Equal(t, expected, out)
+			assert.Equal(t, test.deleted, deleted)
 			assert.Equal(t, test.diags, diags)
 		})
 	}
 }
+
+type funcStep struct {
+	writer  *Writer
+	name    string
+	changes func(*Writer) Changes
+}
+
+func (s *funcStep) Name() string                        { return s.name }
+func (s *funcStep) Description() string                 { return s.name }
+func (s *funcStep) Changes() (Changes, hcl.Diagnostics) { return s.changes(s.writer), nil }
+func (s *funcStep) WithWriter(w *Writer) Step {
+	s.writer = w
+	return s
+}
+
+func setAttributeStep(name, attr string) *funcStep {
+	return &funcStep{
+		name: name,
+		changes: func(w *Writer) Changes {
+			path := filepath.Join(w.Dir(), "main.tf")
+			file, _ := w.File(path)
+			file.Body().SetAttributeValue(attr, cty.True)
+			return Changes{path: &Change{File: file}}
+		},
+	}
+}
+
+func TestStepsChanges(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf": "locals {}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	writer, diags := New(dir)
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	path := filepath.Join(dir, "main.tf")
+	changes, diags := NewSteps(writer, Steps{
+		setAttributeStep("First", "a"),
+		setAttributeStep("Second", "b"),
+	}).Changes()
+
+	assert.Empty(t, diags)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, []string{"First", "Second"}, changes[path].StepNames())
+	assert.Equal(t, []StepChange{
+		{
+			Step: "First",
+			Diff: trimTestConfig(`
+				--- ` + path + `
+				+++ ` + path + `
+				@@ -1 +1,2 @@
+				 locals {}
+				+a = true
+			`),
+		},
+		{
+			Step: "Second",
+			Diff: trimTestConfig(`
+				--- ` + path + `
+				+++ ` + path + `
+				@@ -1,2 +1,3 @@
+				 locals {}
+				 a = true
+				+b = true
+			`),
+		},
+	}, changes[path].Steps)
+	assert.Equal(t, changes[path].Steps[1].Diff, changes.StepDiff("Second"))
+	assert.Empty(t, changes.StepDiff("Third"))
+}
+
+func TestStepsChanges_conflict(t *testing.T) {
+	dir := testTempDir(t, map[string]string{
+		"main.tf": "locals {}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	writer, diags := New(dir)
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	path := filepath.Join(dir, "main.tf")
+	changes, diags := NewSteps(writer, Steps{
+		setAttributeStep("First", "a"),
+		&funcStep{
+			name: "Second",
+			changes: func(w *Writer) Changes {
+				return Changes{path: &Change{File: hclwrite.NewEmptyFile()}}
+			},
+		},
+	}).Changes()
+
+	assert.True(t, diags.HasErrors())
+	assert.Equal(t, "Conflicting changes", diags[0].Summary)
+	assert.Equal(t, []string{"First"}, changes[path].StepNames())
+}
@@ -0,0 +1,12 @@
+data "terraform_remote_state" "app" {
+  backend   = "s3"
+  workspace = "prod"
+
+  config = {
+    # shared state bucket
+    bucket = "terraform-state"
+    key    = "app/terraform.tfstate" // per-app key
+    /* legacy region */
+    region = "us-east-1"
+  }
+}
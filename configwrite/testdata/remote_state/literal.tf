@@ -0,0 +1,16 @@
+data "terraform_remote_state" "staging" {
+  backend   = "s3"
+  workspace = "stg"
+  config = {
+    bucket = "terraform-state"
+    key    = "app/terraform.tfstate"
+  }
+}
+
+data "terraform_remote_state" "other" {
+  backend = "s3"
+  config = {
+    bucket = "terraform-state"
+    key    = "other/terraform.tfstate"
+  }
+}
@@ -0,0 +1,37 @@
+variable "regions" {
+  type = list(string)
+}
+
+# reads the app's outputs
+data "terraform_remote_state" "app" {
+  # one per region
+  count = length(var.regions)
+
+  backend   = "s3"
+  workspace = terraform.workspace
+
+  config = {
+    bucket = "terraform-state"
+    key    = "app/terraform.tfstate"
+    region = var.regions[count.index]
+  }
+
+  # used before the first apply
+  defaults = {
+    vpc_id = ""
+  }
+
+  provider   = terraform.alt
+  depends_on = [null_resource.wait]
+}
+
+data "terraform_remote_state" "apps" {
+  for_each = toset(["a", "b"])
+
+  backend = "s3"
+  config = {
+    bucket = "terraform-state"
+    key    = "app/terraform.tfstate"
+  }
+  workspace = each.key
+}
@@ -1,7 +1,11 @@
 package configwrite
 
 import (
+	"regexp"
 	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTfvars(t *testing.T) {
@@ -54,3 +58,88 @@ func TestTfvars(t *testing.T) {
 		},
 	})
 }
+
+func TestTfvarsVariables(t *testing.T) {
+	testStepChanges(t, stepTests{
+		{
+			name: "incomplete",
+			step: &TfvarsVariables{VarFiles: []string{"prod.tfvars"}},
+			in: map[string]string{
+				"main.tf":               "",
+				"terraform.tfvars":      `foo = "bar"`,
+				"network.auto.tfvars":   `cidr = "10.0.0.0/16"`,
+				"prod.tfvars":           `foo = "baz"`,
+				"unrelated.tfvars":      `foo = "qux"`,
+				"modules/child/main.tf": "",
+			},
+			expected: map[string]string{},
+			deleted:  []string{"network.auto.tfvars", "prod.tfvars", "terraform.tfvars"},
+		},
+		{
+			name: "json",
+			step: &TfvarsVariables{},
+			in: map[string]string{
+				"main.tf":                  "",
+				"terraform.tfvars.json":    `{"foo": "bar"}`,
+				"network.auto.tfvars.json": `{"cidr": "10.0.0.0/16"}`,
+			},
+			expected: map[string]string{},
+			deleted:  []string{"network.auto.tfvars.json", "terraform.tfvars.json"},
+		},
+		{
+			name: "complete",
+			step: &TfvarsVariables{},
+			in: map[string]string{
+				"main.tf": "",
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "missing var file",
+			step: &TfvarsVariables{VarFiles: []string{"prod.tfvars"}},
+			in: map[string]string{
+				"main.tf": "",
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				{
+					Severity: hcl.DiagError,
+					Summary:  "Variable file not found",
+					Detail:   "The variable file prod.tfvars does not exist.",
+					Subject:  &hcl.Range{Filename: "prod.tfvars"},
+				},
+			},
+		},
+	})
+}
+
+func TestTfvarsVariables_Variables(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf": "",
+		"terraform.tfvars": `
+			name     = "app"
+			count    = 3
+			enabled  = true
+			password = "hunter2"
+			subnets  = ["a", "b"]
+			tags     = { team = "platform" }
+		`,
+		"z.auto.tfvars": `
+			name = "override"
+		`,
+	})
+
+	step := &TfvarsVariables{Sensitive: regexp.MustCompile(`password|secret`)}
+	step.WithWriter(writer)
+
+	variables, diags := step.Variables()
+	assert.Empty(t, diags)
+	assert.Equal(t, []Variable{
+		{Key: "count", Value: "3", Filename: "terraform.tfvars"},
+		{Key: "enabled", Value: "true", Filename: "terraform.tfvars"},
+		{Key: "name", Value: "override", Filename: "z.auto.tfvars"},
+		{Key: "password", Value: "hunter2", Sensitive: true, Filename: "terraform.tfvars"},
+		{Key: "subnets", Value: `["a", "b"]`, HCL: true, Filename: "terraform.tfvars"},
+		{Key: "tags", Value: `{ team = "platform" }`, HCL: true, Filename: "terraform.tfvars"},
+	}, variables)
+}
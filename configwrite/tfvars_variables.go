@@ -0,0 +1,146 @@
+package configwrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TfvarsVariables replaces tfvars files with Terraform Cloud workspace variables. Its changes delete the files, so
+// Variables() must be called to read their values before the changes are written.
+type TfvarsVariables struct {
+	writer *Writer
+	// VarFiles are additional variable files, as passed to -var-file, relative to the module
+	VarFiles []string
+	// Sensitive matches the names of variables that should be marked sensitive
+	Sensitive *regexp.Regexp
+}
+
+// Variable is a variable value read from a tfvars file or a workspace-keyed local
+type Variable struct {
+	Key   string
+	Value string
+	// HCL is true when the value is a list, map, or object that must be parsed as HCL
+	HCL       bool
+	Sensitive bool
+	// Filename is the file the value was read from
+	Filename string
+	// Workspace is the existing workspace that the value is set for, or empty if it is set for every workspace
+	Workspace string
+}
+
+func (s *TfvarsVariables) WithWriter(w *Writer) Step {
+	s.writer = w
+	return s
+}
+
+func (s *TfvarsVariables) Name() string {
+	return "Upload tfvars as workspace variables"
+}
+
+// Description returns a description of the step
+func (s *TfvarsVariables) Description() string {
+	return `Terraform Cloud passes workspace variables by writing to terraform.tfvars. Values from tfvars files are uploaded as workspace variables and the files are deleted so that secrets are not kept in the repository (https://www.terraform.io/docs/cloud/workspaces/variables.html)`
+}
+
+// Complete checks if any tfvars files remain
+func (s *TfvarsVariables) Complete() bool {
+	paths, _ := s.paths()
+	return len(paths) == 0
+}
+
+// paths returns the tfvars files in the order Terraform loads them, so that later values take precedence
+func (s *TfvarsVariables) paths() ([]string, hcl.Diagnostics) {
+	paths, diags := s.writer.tfvarsFiles()
+
+	for _, file := range s.VarFiles {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.writer.Dir(), path)
+		}
+
+		if exists, _ := afero.Exists(s.writer.fs, path); !exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Variable file not found",
+				Detail:   fmt.Sprintf("The variable file %s does not exist.", file),
+				Subject:  &hcl.Range{Filename: path},
+			})
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, diags
+}
+
+// Changes deletes each tfvars file. The files are not parsed since .tfvars.json files are not native syntax.
+func (s *TfvarsVariables) Changes() (Changes, hcl.Diagnostics) {
+	paths, diags := s.paths()
+	changes := make(Changes, len(paths))
+
+	for _, path := range paths {
+		changes[path] = &Change{Delete: true}
+	}
+
+	return changes, diags
+}
+
+// Variables reads the values from every tfvars file, sorted by key. When a variable is set in more
+// than one file, the value from the file that Terraform loads last is used.
+func (s *TfvarsVariables) Variables() ([]Variable, hcl.Diagnostics) {
+	paths, diags := s.paths()
+	variables := make(map[string]Variable)
+
+	for _, path := range paths {
+		values, vDiags := s.writer.parser.LoadValuesFile(path)
+		diags = append(diags, vDiags...)
+
+		for key, value := range values {
+			variable := newVariable(key, value)
+			variable.Filename = path
+			variable.Sensitive = s.Sensitive != nil && s.Sensitive.MatchString(key)
+			variables[key] = variable
+		}
+	}
+
+	out := make([]Variable, 0, len(variables))
+	for _, variable := range variables {
+		out = append(out, variable)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+
+	return out, diags
+}
+
+// newVariable converts a value to the string form used by workspace variables. Strings, numbers, and bools
+// are plain values and all other types are written as HCL.
+func newVariable(key string, value cty.Value) Variable {
+	if value.IsKnown() && !value.IsNull() {
+		switch value.Type() {
+		case cty.String:
+			return Variable{Key: key, Value: value.AsString()}
+		case cty.Number:
+			return Variable{Key: key, Value: value.AsBigFloat().Text('f', -1)}
+		case cty.Bool:
+			return Variable{Key: key, Value: fmt.Sprint(value.True())}
+		}
+	}
+
+	return Variable{
+		Key:   key,
+		Value: string(hclwrite.TokensForValue(value).Bytes()),
+		HCL:   true,
+	}
+}
+
+var _ Step = (*TfvarsVariables)(nil)
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -15,8 +18,14 @@ import (
 type TerraformWorkspace struct {
 	writer   *Writer
 	Variable string
+	// Workspaces are the existing workspaces that the variable is validated against. No validation is generated if
+	// empty or if the module's required_version allows versions of Terraform before validation was supported.
+	Workspaces []string
 }
 
+// validationVersion is the first version of Terraform that supports variable validation without an experiment
+var validationVersion = version.Must(version.NewVersion("0.13.0"))
+
 func (s *TerraformWorkspace) WithWriter(w *Writer) Step {
 	s.writer = w
 	return s
@@ -72,19 +81,67 @@ func (s *TerraformWorkspace) Changes() (Changes, hcl.Diagnostics) {
 		return changes, diags
 	}
 
-	if _, ok := s.writer.Variables()[s.Variable]; !ok {
-		path := filepath.Join(s.writer.Dir(), "variables.tf")
-		file, fDiags := s.writer.File(path)
-		diags = append(diags, fDiags...)
-
-		changes[path] = &Change{
-			File: addWorkspaceVariable(file, s.Variable),
+	if variable, ok := s.writer.Variables()[s.Variable]; ok {
+		if !variable.Type.Equals(cty.String) && !variable.Type.Equals(cty.DynamicPseudoType) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Workspace variable type conflict",
+				Detail:   fmt.Sprintf(`The variable "%s" replaces terraform.workspace, which is a string, but it is declared as %s.`, s.Variable, variable.Type.FriendlyName()),
+				Subject:  &variable.DeclRange,
+			})
 		}
+
+		return changes, diags
+	}
+
+	path := filepath.Join(s.writer.Dir(), "variables.tf")
+	file, fDiags := s.writer.File(path)
+	diags = append(diags, fDiags...)
+
+	workspaces := s.Workspaces
+	if !s.writer.requiresVersion(validationVersion) {
+		workspaces = nil
+	}
+
+	changes[path] = &Change{
+		File: addWorkspaceVariable(file, s.Variable, workspaces),
 	}
 
 	return changes, diags
 }
 
+// Occurrences returns the location of each reference to terraform.workspace that the step replaces, ordered by file and position
+func (s *TerraformWorkspace) Occurrences() ([]hcl.Range, hcl.Diagnostics) {
+	paths, _, diags := s.writer.parser.ConfigDirFiles(s.writer.Dir())
+	sort.Strings(paths)
+
+	var ranges []hcl.Range
+	for _, path := range paths {
+		body, bDiags := s.writer.parser.LoadHCLFile(path)
+		diags = append(diags, bDiags...)
+
+		syntaxBody, ok := body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		var found []hcl.Range
+		hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+			if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && isTerraformWorkspace(expr.Traversal) {
+				found = append(found, expr.SrcRange)
+			}
+			return nil
+		})
+
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].Start.Byte < found[j].Start.Byte
+		})
+		ranges = append(ranges, found...)
+	}
+
+	return ranges, diags
+}
+
 func hasTerraformWorkspace(body *hclwrite.Body) bool {
 	for _, attr := range body.Attributes() {
 		for _, traversal := range attr.Expr().Variables() {
@@ -153,7 +210,8 @@ func changedFiles(sources map[string][]byte, changes Changes) (Changes, hcl.Diag
 	return changed, nil
 }
 
-func addWorkspaceVariable(file *hclwrite.File, name string) *hclwrite.File {
+// addWorkspaceVariable declares the workspace variable, inserted before the first variable whose name sorts after it
+func addWorkspaceVariable(file *hclwrite.File, name string, workspaces []string) *hclwrite.File {
 	variable := hclwrite.NewBlock("variable", []string{name})
 	variable.Body().SetAttributeRaw("type", hclwrite.Tokens{
 		{
@@ -163,17 +221,80 @@ func addWorkspaceVariable(file *hclwrite.File, name string) *hclwrite.File {
 	})
 	variable.Body().SetAttributeValue("description", cty.StringVal(fmt.Sprintf("The %s where the module will be deployed", name)))
 
+	if len(workspaces) != 0 {
+		variable.Body().AppendNewline()
+		variable.Body().AppendBlock(workspaceValidation(name, workspaces))
+	}
+
 	body := file.Body()
 	existing := body.BuildTokens(nil)
+	i := variableInsertIndex(body, existing, name)
 
 	body.Clear()
+	body.AppendUnstructuredTokens(existing[:i])
+	if i == len(existing) {
+		if i != 0 {
+			body.AppendNewline()
+		}
+		body.AppendBlock(variable)
+		return file
+	}
+
 	body.AppendBlock(variable)
 	body.AppendNewline()
-	body.AppendUnstructuredTokens(existing)
+	body.AppendUnstructuredTokens(existing[i:])
 
 	return file
 }
 
+// workspaceValidation returns a validation block that restricts the workspace variable to the existing workspaces
+func workspaceValidation(name string, workspaces []string) *hclwrite.Block {
+	values := make([]cty.Value, len(workspaces))
+	for i, workspace := range workspaces {
+		values[i] = cty.StringVal(workspace)
+	}
+
+	condition := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("contains")},
+		{Type: hclsyntax.TokenOParen, Bytes: []byte("(")},
+	}
+	condition = append(condition, hclwrite.TokensForValue(cty.ListVal(values))...)
+	condition = append(condition, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+	condition = append(condition, traversalTokens("var", name)...)
+	condition = append(condition, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte(")")})
+
+	validation := hclwrite.NewBlock("validation", nil)
+	validation.Body().SetAttributeRaw("condition", condition)
+	validation.Body().SetAttributeValue("error_message", cty.StringVal(fmt.Sprintf("The %s must be one of: %s.", name, strings.Join(workspaces, ", "))))
+
+	return validation
+}
+
+// variableInsertIndex returns the index of the token that a variable should be inserted before to keep variables
+// sorted by name. Comments directly above the following variable stay with it.
+func variableInsertIndex(body *hclwrite.Body, tokens hclwrite.Tokens, name string) int {
+	for _, block := range body.Blocks() {
+		labels := block.Labels()
+		if block.Type() != "variable" || len(labels) != 1 || labels[0] <= name {
+			continue
+		}
+
+		first := block.BuildTokens(nil)[0]
+		for i, token := range tokens {
+			if token != first {
+				continue
+			}
+
+			for i > 0 && tokens[i-1].Type == hclsyntax.TokenComment {
+				i--
+			}
+			return i
+		}
+	}
+
+	return len(tokens)
+}
+
 func tokensEqual(a hclwrite.Tokens, b hclwrite.Tokens) bool {
 	if len(a) != len(b) {
 		return false
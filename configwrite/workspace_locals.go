@@ -0,0 +1,506 @@
+package configwrite
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WorkspaceLocals extracts locals that map workspace names to values, such as local.settings[terraform.workspace],
+// into variables that are set for each workspace. Values are returned from Variables() to upload as workspace
+// variables, or written to a tfvars file for each workspace.
+type WorkspaceLocals struct {
+	writer *Writer
+	// Variable is the variable that replaces terraform.workspace, which is also accepted as the key of a lookup
+	Variable string
+	// Files writes <workspace>.tfvars files, which Terraform does not load automatically, instead of returning the
+	// values from Variables()
+	Files bool
+}
+
+// workspaceLocal is a local whose value is an object keyed by workspace name and that is only read with the current workspace
+type workspaceLocal struct {
+	Name   string
+	Values map[string]cty.Value
+	// Keys are the workspace expressions used as the key of each lookup
+	Keys []hcl.Range
+}
+
+func (s *WorkspaceLocals) WithWriter(w *Writer) Step {
+	s.writer = w
+	return s
+}
+
+func (s *WorkspaceLocals) Name() string {
+	return "Extract workspace-keyed locals"
+}
+
+// Description returns a description of the step
+func (s *WorkspaceLocals) Description() string {
+	return `Terraform Cloud sets values that differ between workspaces with workspace variables. Locals that are looked up by terraform.workspace are replaced with variables and each workspace's values are uploaded as workspace variables or written to <workspace>.tfvars (https://www.terraform.io/docs/cloud/workspaces/variables.html)`
+}
+
+// Complete checks if any workspace-keyed locals remain
+func (s *WorkspaceLocals) Complete() bool {
+	locals, _ := s.locals()
+	return len(locals) == 0
+}
+
+// Changes replaces each workspace lookup with a variable and removes the local
+func (s *WorkspaceLocals) Changes() (Changes, hcl.Diagnostics) {
+	changes := make(Changes)
+	locals, diags := s.locals()
+	if len(locals) == 0 {
+		return changes, diags
+	}
+
+	names := make(map[string]bool, len(locals))
+	for _, local := range locals {
+		names[local.Name] = true
+	}
+
+	paths, _, pDiags := s.writer.parser.ConfigDirFiles(s.writer.Dir())
+	diags = append(diags, pDiags...)
+
+	for _, path := range paths {
+		file, fDiags := s.writer.File(path)
+		diags = append(diags, fDiags...)
+		if file == nil {
+			continue
+		}
+
+		replaced := replaceWorkspaceLookups(file.Body(), names, s.keyTokens())
+		removed := removeLocals(file, names)
+		if replaced || removed {
+			changes[path] = &Change{File: file}
+		}
+	}
+
+	path := filepath.Join(s.writer.Dir(), "variables.tf")
+	file, fDiags := s.writer.File(path)
+	diags = append(diags, fDiags...)
+	for _, local := range locals {
+		appendWorkspaceLocalVariable(file, local)
+	}
+	changes[path] = &Change{File: file}
+
+	if !s.Files {
+		return changes, diags
+	}
+
+	workspaces := workspaceLocalKeys(locals)
+	filenames := make([]string, len(workspaces))
+	for i, workspace := range workspaces {
+		filenames[i] = workspace + ".tfvars"
+	}
+	diags = append(diags, &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Workspace variables must be set",
+		Detail:   fmt.Sprintf("The values of the extracted locals are written to %s, which Terraform does not load automatically. Set them as variables of each Terraform Cloud workspace, or upload them as workspace variables instead of writing files. For runs on your machine, pass -var-file=<workspace>.tfvars to terraform.", strings.Join(filenames, ", ")),
+	})
+
+	for _, workspace := range workspaces {
+		path := filepath.Join(s.writer.Dir(), workspace+".tfvars")
+		file, fDiags := s.writer.File(path)
+		diags = append(diags, fDiags...)
+
+		for _, local := range locals {
+			if value, ok := local.Values[workspace]; ok {
+				file.Body().SetAttributeValue(local.Name, value)
+			}
+		}
+
+		changes[path] = &Change{File: file}
+	}
+
+	return changes, diags
+}
+
+// Variables returns the value of each extracted local for each workspace, sorted by workspace and key. It returns
+// nothing if the values are written to files.
+func (s *WorkspaceLocals) Variables() ([]Variable, hcl.Diagnostics) {
+	if s.Files {
+		return nil, nil
+	}
+
+	locals, diags := s.locals()
+
+	var variables []Variable
+	for _, workspace := range workspaceLocalKeys(locals) {
+		for _, local := range locals {
+			value, ok := local.Values[workspace]
+			if !ok {
+				continue
+			}
+
+			variable := newVariable(local.Name, value)
+			variable.Workspace = workspace
+			variable.Filename = s.writer.module.Locals[local.Name].DeclRange.Filename
+			variables = append(variables, variable)
+		}
+	}
+
+	return variables, diags
+}
+
+// Lookups returns the location of each workspace key in the lookups that are replaced with variables
+func (s *WorkspaceLocals) Lookups() ([]hcl.Range, hcl.Diagnostics) {
+	locals, diags := s.locals()
+
+	var ranges []hcl.Range
+	for _, local := range locals {
+		ranges = append(ranges, local.Keys...)
+	}
+	return ranges, diags
+}
+
+// keyTokens returns the expressions that are accepted as the key of a workspace lookup
+func (s *WorkspaceLocals) keyTokens() []hclwrite.Tokens {
+	keys := []hclwrite.Tokens{traversalTokens("terraform", "workspace")}
+	if s.Variable != "" {
+		keys = append(keys, traversalTokens("var", s.Variable))
+	}
+	return keys
+}
+
+// isWorkspaceKey returns true if an expression is terraform.workspace or the workspace variable
+func (s *WorkspaceLocals) isWorkspaceKey(expr hclsyntax.Expression) bool {
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok {
+		return false
+	}
+
+	if isTerraformWorkspace(traversal.Traversal) {
+		return true
+	}
+
+	name, ok := localName(traversal.Traversal, "var")
+	return ok && s.Variable != "" && name == s.Variable
+}
+
+// locals finds the locals that can be extracted, sorted by name. Locals that are looked up by workspace but are
+// also used in other ways or cannot be evaluated are reported as warnings.
+func (s *WorkspaceLocals) locals() ([]workspaceLocal, hcl.Diagnostics) {
+	paths, _, diags := s.writer.parser.ConfigDirFiles(s.writer.Dir())
+
+	// lookups are the local.<name> expressions that are indexed by the workspace, by local name
+	lookups := make(map[string]map[hcl.Range]bool)
+	// keys are the workspace expressions that index each local
+	keys := make(map[string][]hcl.Range)
+	// references are all other local.<name> expressions
+	references := make(map[string][]hcl.Range)
+
+	for _, path := range paths {
+		body, bDiags := s.writer.parser.LoadHCLFile(path)
+		diags = append(diags, bDiags...)
+
+		syntaxBody, ok := body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch expr := node.(type) {
+			case *hclsyntax.IndexExpr:
+				collection, ok := expr.Collection.(*hclsyntax.ScopeTraversalExpr)
+				if !ok || len(collection.Traversal) != 2 || !s.isWorkspaceKey(expr.Key) {
+					return nil
+				}
+
+				if name, ok := localName(collection.Traversal, "local"); ok {
+					if lookups[name] == nil {
+						lookups[name] = make(map[hcl.Range]bool)
+					}
+					lookups[name][collection.SrcRange] = true
+					keys[name] = append(keys[name], expr.Key.Range())
+				}
+			case *hclsyntax.ScopeTraversalExpr:
+				if name, ok := localName(expr.Traversal, "local"); ok {
+					references[name] = append(references[name], expr.SrcRange)
+				}
+			}
+			return nil
+		})
+	}
+
+	ctx, eDiags := s.writer.EvalContext()
+	diags = append(diags, eDiags...)
+
+	names := make([]string, 0, len(lookups))
+	for name := range lookups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var locals []workspaceLocal
+	for _, name := range names {
+		local, ok := s.writer.module.Locals[name]
+		if !ok {
+			continue
+		}
+
+		if other := otherReference(references[name], lookups[name]); other != nil {
+			diags = append(diags, workspaceLocalNotExtracted(name, other, "it is also used without a workspace key"))
+			continue
+		}
+
+		if _, ok := s.writer.Variables()[name]; ok {
+			diags = append(diags, workspaceLocalNotExtracted(name, &local.DeclRange, fmt.Sprintf(`a variable named "%s" is already declared`, name)))
+			continue
+		}
+
+		value, vDiags := local.Expr.Value(ctx)
+		if vDiags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() || !(value.Type().IsObjectType() || value.Type().IsMapType()) {
+			diags = append(diags, workspaceLocalNotExtracted(name, &local.DeclRange, "its value is not a map of workspace names that can be determined without running Terraform"))
+			continue
+		}
+
+		values := value.AsValueMap()
+		if key := invalidWorkspaceName(values); key != "" {
+			diags = append(diags, workspaceLocalNotExtracted(name, &local.DeclRange, fmt.Sprintf(`its key "%s" is not a valid workspace name`, key)))
+			continue
+		}
+
+		locals = append(locals, workspaceLocal{
+			Name:   name,
+			Values: values,
+			Keys:   keys[name],
+		})
+	}
+
+	return locals, diags
+}
+
+// otherReference returns a reference that is not a workspace lookup, or nil if there are none
+func otherReference(references []hcl.Range, lookups map[hcl.Range]bool) *hcl.Range {
+	for _, rng := range references {
+		if !lookups[rng] {
+			rng := rng
+			return &rng
+		}
+	}
+	return nil
+}
+
+// invalidWorkspaceName returns the first key, sorted, that is not a valid workspace name, or an empty string if
+// every key is valid. Keys are used as filenames, so names that Terraform would reject, such as those that contain
+// a path separator, are not extracted.
+func invalidWorkspaceName(values map[string]cty.Value) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "" || key != url.PathEscape(key) || strings.HasPrefix(key, ".") {
+			return key
+		}
+	}
+	return ""
+}
+
+func workspaceLocalNotExtracted(name string, subject *hcl.Range, reason string) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Workspace local not extracted",
+		Detail:   fmt.Sprintf("local.%s is looked up by workspace but cannot be replaced with a variable because %s.", name, reason),
+		Subject:  subject,
+	}
+}
+
+// localName returns the name of a two-part reference like local.<name> or var.<name>
+func localName(traversal hcl.Traversal, root string) (string, bool) {
+	if len(traversal) < 2 || traversal.RootName() != root {
+		return "", false
+	}
+
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return attr.Name, true
+}
+
+// workspaceLocalKeys returns the workspace names from every local, sorted
+func workspaceLocalKeys(locals []workspaceLocal) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, local := range locals {
+		for key := range local.Values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// replaceWorkspaceLookups replaces local.<name>[<key>] with var.<name> in every expression of a body, returning
+// whether any lookups were replaced
+func replaceWorkspaceLookups(body *hclwrite.Body, names map[string]bool, keys []hclwrite.Tokens) bool {
+	replaced := false
+	for name, attr := range body.Attributes() {
+		if tokens, ok := replaceLookupTokens(attr.Expr().BuildTokens(nil), names, keys); ok {
+			body.SetAttributeRaw(name, tokens)
+			replaced = true
+		}
+	}
+
+	for _, block := range body.Blocks() {
+		if replaceWorkspaceLookups(block.Body(), names, keys) {
+			replaced = true
+		}
+	}
+
+	return replaced
+}
+
+func replaceLookupTokens(tokens hclwrite.Tokens, names map[string]bool, keys []hclwrite.Tokens) (hclwrite.Tokens, bool) {
+	out := make(hclwrite.Tokens, 0, len(tokens))
+	replaced := false
+
+	for i := 0; i < len(tokens); i++ {
+		n := lookupLength(tokens[i:], names, keys)
+		if n == 0 {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		variable := traversalTokens("var", string(tokens[i+2].Bytes))
+		variable[0].SpacesBefore = tokens[i].SpacesBefore
+		out = append(out, variable...)
+		i += n - 1
+		replaced = true
+	}
+
+	return out, replaced
+}
+
+// lookupLength returns the number of tokens in a local.<name>[<key>] lookup at the start of tokens, or 0 if there is none
+func lookupLength(tokens hclwrite.Tokens, names map[string]bool, keys []hclwrite.Tokens) int {
+	if len(tokens) < 4 ||
+		tokens[0].Type != hclsyntax.TokenIdent || string(tokens[0].Bytes) != "local" ||
+		tokens[1].Type != hclsyntax.TokenDot ||
+		tokens[2].Type != hclsyntax.TokenIdent || !names[string(tokens[2].Bytes)] ||
+		tokens[3].Type != hclsyntax.TokenOBrack {
+		return 0
+	}
+
+	for _, key := range keys {
+		end := 4 + len(key)
+		if len(tokens) > end && tokensEqual(tokens[4:end], key) && tokens[end].Type == hclsyntax.TokenCBrack {
+			return end + 1
+		}
+	}
+
+	return 0
+}
+
+// removeLocals removes the named locals from every locals block, and removes blocks that are left empty
+func removeLocals(file *hclwrite.File, names map[string]bool) bool {
+	body := file.Body()
+	removed, emptied := false, false
+
+	for _, block := range body.Blocks() {
+		if block.Type() != "locals" {
+			continue
+		}
+
+		for name := range block.Body().Attributes() {
+			if names[name] {
+				block.Body().RemoveAttribute(name)
+				removed = true
+			}
+		}
+
+		if len(block.Body().Attributes()) == 0 && len(block.Body().Blocks()) == 0 {
+			body.RemoveBlock(block)
+			emptied = true
+		}
+	}
+
+	if emptied {
+		trimBlankLines(file)
+	}
+	return removed
+}
+
+// trimBlankLines removes the blank lines that are left at the start of a file, or doubled between blocks, when a
+// block is removed. The tokens built from a file are the tokens of its body, so the extra newlines are emptied in
+// place and the blocks that other steps hold keep belonging to the file.
+func trimBlankLines(file *hclwrite.File) {
+	newlines, start := 0, true
+	for _, token := range file.BuildTokens(nil) {
+		if len(token.Bytes) == 0 {
+			continue
+		}
+
+		if token.Type != hclsyntax.TokenNewline {
+			newlines, start = 0, false
+			continue
+		}
+
+		newlines++
+		if start || newlines > 2 {
+			token.Bytes = nil
+		}
+	}
+}
+
+// appendWorkspaceLocalVariable declares a variable that replaces a workspace-keyed local
+func appendWorkspaceLocalVariable(file *hclwrite.File, local workspaceLocal) {
+	variable := hclwrite.NewBlock("variable", []string{local.Name})
+	if typ := workspaceLocalType(local.Values); typ != "" {
+		variable.Body().SetAttributeRaw("type", hclwrite.Tokens{
+			{Type: hclsyntax.TokenIdent, Bytes: []byte(typ)},
+		})
+	}
+	variable.Body().SetAttributeValue("description", cty.StringVal(fmt.Sprintf("Set for each workspace, replacing local.%s", local.Name)))
+
+	body := file.Body()
+	if len(body.Attributes()) != 0 || len(body.Blocks()) != 0 {
+		body.AppendNewline()
+	}
+	body.AppendBlock(variable)
+}
+
+// workspaceLocalType returns the type constraint for a variable if every workspace has a value of the same primitive type
+func workspaceLocalType(values map[string]cty.Value) string {
+	var typ cty.Type
+	for _, value := range values {
+		if typ != cty.NilType && !value.Type().Equals(typ) {
+			return ""
+		}
+		typ = value.Type()
+	}
+
+	switch typ {
+	case cty.String:
+		return "string"
+	case cty.Number:
+		return "number"
+	case cty.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+func traversalTokens(root, name string) hclwrite.Tokens {
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(root)},
+		{Type: hclsyntax.TokenDot, Bytes: []byte(".")},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+	}
+}
+
+var _ Step = (*WorkspaceLocals)(nil)
@@ -0,0 +1,294 @@
+package configwrite
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestWorkspaceLocals(t *testing.T) {
+	testStepChanges(t, stepTests{
+		{
+			name: "tfvars",
+			step: &WorkspaceLocals{Variable: "environment", Files: true},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						instance_type = {
+							staging    = "t3.small"
+							production = "m5.large"
+						}
+						replicas = {
+							staging    = 1
+							production = 3
+						}
+						name = "app"
+					}
+
+					resource "aws_instance" "app" {
+						count         = local.replicas[terraform.workspace]
+						instance_type = local.instance_type[var.environment]
+
+						tags = {
+							Name = "${local.name}-${local.instance_type[terraform.workspace]}"
+						}
+					}
+				`,
+				"variables.tf": `
+					variable "environment" {}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					locals {
+						name = "app"
+					}
+
+					resource "aws_instance" "app" {
+						count         = var.replicas
+						instance_type = var.instance_type
+
+						tags = {
+							Name = "${local.name}-${var.instance_type}"
+						}
+					}
+				`,
+				"variables.tf": `
+					variable "environment" {}
+
+					variable "instance_type" {
+						type        = string
+						description = "Set for each workspace, replacing local.instance_type"
+					}
+
+					variable "replicas" {
+						type        = number
+						description = "Set for each workspace, replacing local.replicas"
+					}
+				`,
+				"production.tfvars": `
+					instance_type = "m5.large"
+					replicas      = 3
+				`,
+				"staging.tfvars": `
+					instance_type = "t3.small"
+					replicas      = 1
+				`,
+			},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Workspace variables must be set",
+					Detail:   "The values of the extracted locals are written to production.tfvars, staging.tfvars, which Terraform does not load automatically. Set them as variables of each Terraform Cloud workspace, or upload them as workspace variables instead of writing files. For runs on your machine, pass -var-file=<workspace>.tfvars to terraform.",
+				},
+			},
+		},
+		{
+			name: "upload",
+			step: &WorkspaceLocals{},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						settings = {
+							staging    = { size = 1 }
+							production = { size = 3 }
+						}
+					}
+
+					output "size" {
+						value = local.settings[terraform.workspace].size
+					}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					output "size" {
+						value = var.settings.size
+					}
+				`,
+				"variables.tf": `
+					variable "settings" {
+						description = "Set for each workspace, replacing local.settings"
+					}
+				`,
+			},
+		},
+		{
+			name: "other references",
+			step: &WorkspaceLocals{},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						settings = {
+							staging = 1
+						}
+					}
+
+					output "current" {
+						value = local.settings[terraform.workspace]
+					}
+
+					output "all" {
+						value = local.settings
+					}
+				`,
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Workspace local not extracted",
+					Detail:   "local.settings is looked up by workspace but cannot be replaced with a variable because it is also used without a workspace key.",
+					Subject: &hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 12, Column: 11, Byte: 140},
+						End:      hcl.Pos{Line: 12, Column: 25, Byte: 154},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown values",
+			step: &WorkspaceLocals{},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						settings = {
+							staging = aws_instance.app.id
+						}
+					}
+
+					output "current" {
+						value = local.settings[terraform.workspace]
+					}
+				`,
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Workspace local not extracted",
+					Detail:   "local.settings is looked up by workspace but cannot be replaced with a variable because its value is not a map of workspace names that can be determined without running Terraform.",
+					Subject: &hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 2, Column: 3, Byte: 11},
+						End:      hcl.Pos{Line: 4, Column: 4, Byte: 61},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid workspace name",
+			step: &WorkspaceLocals{},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						settings = {
+							staging      = 1
+							"../main.tf" = 2
+						}
+					}
+
+					output "current" {
+						value = local.settings[terraform.workspace]
+					}
+				`,
+			},
+			expected: map[string]string{},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Workspace local not extracted",
+					Detail:   `local.settings is looked up by workspace but cannot be replaced with a variable because its key "../main.tf" is not a valid workspace name.`,
+					Subject: &hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 2, Column: 3, Byte: 11},
+						End:      hcl.Pos{Line: 5, Column: 4, Byte: 69},
+					},
+				},
+			},
+		},
+		{
+			name: "complete",
+			step: &WorkspaceLocals{},
+			in: map[string]string{
+				"main.tf": `
+					variable "replicas" {}
+
+					output "replicas" {
+						value = var.replicas
+					}
+				`,
+			},
+			expected: map[string]string{},
+		},
+	})
+}
+
+func TestWorkspaceLocals_Variables(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf": `
+			locals {
+				replicas = {
+					staging    = 1
+					production = 3
+				}
+				tags = {
+					production = { tier = "critical" }
+				}
+			}
+
+			output "replicas" {
+				value = local.replicas[terraform.workspace]
+			}
+
+			output "tags" {
+				value = local.tags[terraform.workspace]
+			}
+		`,
+	})
+
+	step := &WorkspaceLocals{}
+	step.WithWriter(writer)
+
+	variables, diags := step.Variables()
+	assert.Empty(t, diags)
+	assert.Equal(t, []Variable{
+		{Key: "replicas", Value: "3", Filename: "main.tf", Workspace: "production"},
+		{Key: "tags", Value: `{ tier = "critical" }`, HCL: true, Filename: "main.tf", Workspace: "production"},
+		{Key: "replicas", Value: "1", Filename: "main.tf", Workspace: "staging"},
+	}, variables)
+	assert.False(t, step.Complete())
+}
+
+func TestRemoveLocals(t *testing.T) {
+	file := testParseFile(t, `locals {
+  a = 1
+}
+
+resource "null_resource" "app" {}
+
+locals {
+  b = 2
+}
+
+output "app" {
+  value = 1
+}
+`)
+
+	// blocks that other steps hold are still part of the file
+	output := file.Body().FirstMatchingBlock("output", []string{"app"})
+
+	assert.True(t, removeLocals(file, map[string]bool{"a": true, "b": true}))
+	output.Body().SetAttributeValue("value", cty.NumberIntVal(2))
+
+	assert.Equal(t, `resource "null_resource" "app" {}
+
+output "app" {
+  value = 2
+}
+`, string(file.Bytes()))
+}
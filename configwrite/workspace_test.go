@@ -1,7 +1,12 @@
 package configwrite
 
 import (
+	"fmt"
+	"os"
 	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTerraformWorkspace(t *testing.T) {
@@ -79,5 +84,309 @@ func TestTerraformWorkspace(t *testing.T) {
 			},
 			expected: map[string]string{},
 		},
+		{
+			name: "expressions",
+			step: &TerraformWorkspace{Variable: "environment"},
+			in: map[string]string{
+				"main.tf": `
+					locals {
+						env_config = {
+							production = { size = "large" }
+						}
+						config = local.env_config[terraform.workspace]
+						size   = lookup(local.env_config, terraform.workspace, {}).size
+						names  = [for name in var.names : "${terraform.workspace}-${name}"]
+						tags   = { for k, v in var.tags : k => "${v}-${terraform.workspace}" if k != terraform.workspace }
+					}
+
+					resource "aws_s3_bucket" "logs" {
+						bucket = "${terraform.workspace}-logs"
+
+						policy = <<EOF
+					{
+					  "Sid": "${terraform.workspace}"
+					}
+					EOF
+
+						dynamic "tag" {
+							for_each = var.tags
+							content {
+								key   = tag.key
+								value = "${tag.value}-${terraform.workspace}"
+							}
+						}
+					}
+				`,
+				"variables.tf": `
+					variable "names" {}
+
+					variable "tags" {}
+
+					variable "environment" {}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					locals {
+						env_config = {
+							production = { size = "large" }
+						}
+						config = local.env_config[var.environment]
+						size   = lookup(local.env_config, var.environment, {}).size
+						names  = [for name in var.names : "${var.environment}-${name}"]
+						tags   = { for k, v in var.tags : k => "${v}-${var.environment}" if k != var.environment }
+					}
+
+					resource "aws_s3_bucket" "logs" {
+						bucket = "${var.environment}-logs"
+
+						policy = <<EOF
+					{
+					  "Sid": "${var.environment}"
+					}
+					EOF
+
+						dynamic "tag" {
+							for_each = var.tags
+							content {
+								key   = tag.key
+								value = "${tag.value}-${var.environment}"
+							}
+						}
+					}
+				`,
+			},
+		},
+		{
+			name: "validation",
+			step: &TerraformWorkspace{Variable: "environment", Workspaces: []string{"production", "staging"}},
+			in: map[string]string{
+				"main.tf": `
+					terraform {
+						required_version = ">= 0.13"
+					}
+
+					output "workspace" {
+						value = terraform.workspace
+					}
+				`,
+				"variables.tf": `
+					variable "bar" {}
+
+					# the region to deploy to
+					variable "region" {}
+
+					variable "zone" {}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					terraform {
+						required_version = ">= 0.13"
+					}
+
+					output "workspace" {
+						value = var.environment
+					}
+				`,
+				"variables.tf": `
+					variable "bar" {}
+
+					variable "environment" {
+						type        = string
+						description = "The environment where the module will be deployed"
+
+						validation {
+							condition     = contains(["production", "staging"], var.environment)
+							error_message = "The environment must be one of: production, staging."
+						}
+					}
+
+					# the region to deploy to
+					variable "region" {}
+
+					variable "zone" {}
+				`,
+			},
+		},
+		{
+			name: "validation unsupported",
+			step: &TerraformWorkspace{Variable: "environment", Workspaces: []string{"production", "staging"}},
+			in: map[string]string{
+				"main.tf": `
+					terraform {
+						required_version = ">= 0.12"
+					}
+
+					output "workspace" {
+						value = terraform.workspace
+					}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					terraform {
+						required_version = ">= 0.12"
+					}
+
+					output "workspace" {
+						value = var.environment
+					}
+				`,
+				"variables.tf": `
+					variable "environment" {
+						type        = string
+						description = "The environment where the module will be deployed"
+					}
+				`,
+			},
+		},
+		{
+			name: "appended",
+			step: &TerraformWorkspace{Variable: "environment"},
+			in: map[string]string{
+				"main.tf": `
+					variable "bar" {}
+
+					output "workspace" {
+						value = terraform.workspace
+					}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					variable "bar" {}
+
+					output "workspace" {
+						value = var.environment
+					}
+				`,
+				"variables.tf": `
+					variable "environment" {
+						type        = string
+						description = "The environment where the module will be deployed"
+					}
+				`,
+			},
+		},
+		{
+			name: "type conflict",
+			step: &TerraformWorkspace{Variable: "environment"},
+			in: map[string]string{
+				"main.tf": `
+					variable "environment" {
+						type = number
+					}
+
+					output "workspace" {
+						value = terraform.workspace
+					}
+				`,
+			},
+			expected: map[string]string{
+				"main.tf": `
+					variable "environment" {
+						type = number
+					}
+
+					output "workspace" {
+						value = var.environment
+					}
+				`,
+			},
+			diags: hcl.Diagnostics{
+				&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Workspace variable type conflict",
+					Detail:   `The variable "environment" replaces terraform.workspace, which is a string, but it is declared as number.`,
+					Subject: &hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 1, Column: 1, Byte: 0},
+						End:      hcl.Pos{Line: 1, Column: 23, Byte: 22},
+					},
+				},
+			},
+		},
 	})
 }
+
+func TestTerraformWorkspace_reparse(t *testing.T) {
+	for _, constraint := range []string{">= 0.12", ">= 0.13"} {
+		t.Run(constraint, func(t *testing.T) {
+			dir := testTempDir(t, map[string]string{
+				"main.tf": "terraform {\n  required_version = \"" + constraint + "\"\n}\n\noutput \"workspace\" {\n  value = terraform.workspace\n}\n",
+			})
+			defer os.RemoveAll(dir)
+
+			writer, diags := New(dir)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			step := &TerraformWorkspace{Variable: "environment", Workspaces: []string{"production", "staging"}}
+			changes, diags := step.WithWriter(writer).Changes()
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			if err := changes.WriteFiles(); err != nil {
+				t.Fatal(err)
+			}
+
+			writer, diags = New(dir)
+			assert.Empty(t, diags)
+			if assert.NotNil(t, writer) {
+				assert.Contains(t, writer.Variables(), "environment")
+			}
+		})
+	}
+}
+
+func TestTerraformWorkspaceOccurrences(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"outputs.tf": `
+			output "workspace" {
+				value = terraform.workspace
+			}
+		`,
+		"main.tf": `
+			locals {
+				config = local.env_config[terraform.workspace]
+				names  = [for name in var.names : "${terraform.workspace}-${name}"]
+			}
+
+			resource "aws_s3_bucket" "logs" {
+				policy = <<EOF
+			{"Sid": "${terraform.workspace}"}
+			EOF
+
+				dynamic "tag" {
+					for_each = var.tags
+					content {
+						value = lookup(var.tags, terraform.workspace)
+					}
+				}
+			}
+		`,
+	})
+
+	step := &TerraformWorkspace{Variable: "environment"}
+	occurrences, diags := step.WithWriter(writer).(*TerraformWorkspace).Occurrences()
+	assert.Empty(t, diags)
+
+	lines := make([]string, len(occurrences))
+	for i, occurrence := range occurrences {
+		lines[i] = occurrenceLine(occurrence)
+	}
+
+	assert.Equal(t, []string{
+		"main.tf:2",
+		"main.tf:3",
+		"main.tf:8",
+		"main.tf:14",
+		"outputs.tf:2",
+	}, lines)
+}
+
+func occurrenceLine(r hcl.Range) string {
+	return fmt.Sprintf("%s:%d", r.Filename, r.Start.Line)
+}
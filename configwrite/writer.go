@@ -3,11 +3,16 @@ package configwrite
 import (
 	"fmt"
 	"os"
+	"regexp"
 
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/hashicorp/terraform/configs"
 	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func New(path string) (*Writer, hcl.Diagnostics) {
@@ -34,6 +39,8 @@ func newWriter(path string, fs afero.Fs) (*Writer, hcl.Diagnostics) {
 	}
 
 	module, diags := parser.LoadConfigDir(path)
+	diags = withoutCloudBlockDiags(diags, cloudBlockRanges(fs, parser, path))
+	diags = withoutValidationDiags(diags, module)
 
 	return &Writer{
 		fs:     fs,
@@ -49,6 +56,16 @@ type Writer struct {
 	parser *configs.Parser
 	module *configs.Module
 	files  map[string]*hclwrite.File
+
+	// backendConfig is partial backend configuration that overrides the backend block
+	backendConfig map[string]cty.Value
+	// backendConfigFiles are the partial configuration files that backendConfig was read from
+	backendConfigFiles []string
+}
+
+// ShareFiles makes the writer use the file cache of another writer so that changes from both writers apply to the same files
+func (w *Writer) ShareFiles(other *Writer) {
+	w.files = other.files
 }
 
 // Dir returns the module directory
@@ -66,11 +83,89 @@ func (w *Writer) HasBackend() bool {
 	return w.Backend() != nil
 }
 
+// HasCloud returns true if the module has a "cloud" block in its terraform settings
+func (w *Writer) HasCloud() bool {
+	files, _, _ := w.parser.ConfigDirFiles(w.Dir())
+	for _, path := range files {
+		file, diags := w.File(path)
+		if diags.HasErrors() {
+			continue
+		}
+
+		for _, block := range file.Body().Blocks() {
+			if block.Type() != "terraform" {
+				continue
+			}
+
+			for _, child := range block.Body().Blocks() {
+				if child.Type() == "cloud" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
 // Variables returns the declared variables for the module
 func (w *Writer) Variables() map[string]*configs.Variable {
 	return w.module.Variables
 }
 
+// RequiredVersions returns the module's required_version constraints
+func (w *Writer) RequiredVersions() []configs.VersionConstraint {
+	return w.module.CoreVersionConstraints
+}
+
+// requiresVersion returns true if the module's required_version constraints exclude every version before v
+func (w *Writer) requiresVersion(v *version.Version) bool {
+	for _, constraint := range w.RequiredVersions() {
+		for _, c := range constraint.Required {
+			if lower := lowerBound(c); lower != nil && lower.GreaterThanOrEqual(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// constraintPattern matches a version constraint with an operator that sets a lower bound
+var constraintPattern = regexp.MustCompile(`^\s*(>=|>|~>|=)?\s*v?(\S+)\s*$`)
+
+// lowerBound returns the version that a constraint requires at least, or nil if it has no lower bound. For ">", the
+// version itself is returned, which is lower than any version that satisfies the constraint.
+func lowerBound(c *version.Constraint) *version.Version {
+	match := constraintPattern.FindStringSubmatch(c.String())
+	if match == nil {
+		return nil
+	}
+
+	v, err := version.NewVersion(match[2])
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// LocalBackend returns the local backend of the module with its "path" and "workspace_dir" settings, including
+// partial configuration from SetBackendConfig, or nil if the module uses another backend. Modules without a backend
+// use the local backend.
+func (w *Writer) LocalBackend() *state.Local {
+	local := &state.Local{Dir: w.Dir()}
+	if !w.HasBackend() {
+		return local
+	}
+	if w.Backend().Type != "local" {
+		return nil
+	}
+
+	config := w.backendStateConfig()
+	local.Path, _ = configString(config.Values, "path", "")
+	local.WorkspaceDir, _ = configString(config.Values, "workspace_dir", "")
+	return local
+}
+
 // RemoteStateDataSources returns a list of remote state data sources defined for the module
 func (w *Writer) RemoteStateDataSources() []*configs.Resource {
 	resources := make([]*configs.Resource, 0)
@@ -115,3 +210,82 @@ func (w *Writer) File(path string) (*hclwrite.File, hcl.Diagnostics) {
 
 	return file, diags
 }
+
+// withoutCloudBlockDiags removes errors for "cloud" blocks, which were added in Terraform 1.1 and are unknown to the
+// configuration parser. Errors are matched by the range of the block type so that other errors are kept.
+func withoutCloudBlockDiags(diags hcl.Diagnostics, cloudBlocks []hcl.Range) hcl.Diagnostics {
+	var out hcl.Diagnostics
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError && diag.Subject != nil && containsRange(cloudBlocks, *diag.Subject) {
+			continue
+		}
+		out = append(out, diag)
+	}
+	return out
+}
+
+// withoutValidationDiags removes errors for variable validation blocks, which are experimental in the configuration
+// parser but supported without an experiment since Terraform 0.13. Errors are matched by the range of each
+// validation block so that other errors are kept.
+func withoutValidationDiags(diags hcl.Diagnostics, module *configs.Module) hcl.Diagnostics {
+	if module == nil {
+		return diags
+	}
+
+	var validations []hcl.Range
+	for _, variable := range module.Variables {
+		for _, validation := range variable.Validations {
+			validations = append(validations, validation.DeclRange)
+		}
+	}
+
+	var out hcl.Diagnostics
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError && diag.Summary == "Custom variable validation is experimental" && diag.Subject != nil && containsRange(validations, *diag.Subject) {
+			continue
+		}
+		out = append(out, diag)
+	}
+	return out
+}
+
+// cloudBlockRanges returns the type range of each "cloud" block in the terraform settings of the module's files
+func cloudBlockRanges(fs afero.Fs, parser *configs.Parser, dir string) []hcl.Range {
+	primary, override, _ := parser.ConfigDirFiles(dir)
+
+	var ranges []hcl.Range
+	for _, path := range append(primary, override...) {
+		src, err := afero.ReadFile(fs, path)
+		if err != nil {
+			continue
+		}
+
+		file, _ := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, child := range block.Body.Blocks {
+				if child.Type == "cloud" {
+					ranges = append(ranges, child.TypeRange)
+				}
+			}
+		}
+	}
+
+	return ranges
+}
+
+func containsRange(ranges []hcl.Range, rng hcl.Range) bool {
+	for _, r := range ranges {
+		if r.Filename == rng.Filename && r.Start.Byte == rng.Start.Byte && r.End.Byte == rng.End.Byte {
+			return true
+		}
+	}
+	return false
+}
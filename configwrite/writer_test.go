@@ -5,8 +5,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bendrucker/terraform-cloud-migrate/state"
 	"github.com/lithammer/dedent"
 	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
 )
 
 func newTestWriter(t *testing.T, path string, setup func(afero.Fs)) *Writer {
@@ -41,3 +43,132 @@ func trimTestConfig(config string) string {
 	config = strings.TrimLeft(config, "\n")
 	return config
 }
+
+func TestWriterLocalBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		args     []string
+		expected *state.Local
+	}{
+		{
+			name:     "none",
+			expected: &state.Local{},
+		},
+		{
+			name: "local",
+			config: `
+				terraform {
+					backend "local" {
+						path = "state/default.tfstate"
+					}
+				}
+			`,
+			args:     []string{"workspace_dir=states"},
+			expected: &state.Local{Path: "state/default.tfstate", WorkspaceDir: "states"},
+		},
+		{
+			name: "other",
+			config: `
+				terraform {
+					backend "s3" {}
+				}
+			`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := newTestModule(t, map[string]string{"main.tf": tc.config})
+			if diags := writer.SetBackendConfig(tc.args); diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			assert.Equal(t, tc.expected, writer.LocalBackend())
+		})
+	}
+}
+
+func TestWriterRequiredVersions(t *testing.T) {
+	writer := newTestModule(t, map[string]string{
+		"main.tf": `
+			terraform {
+				required_version = ">= 0.13, < 2.0.0"
+
+				cloud {}
+			}
+		`,
+	})
+
+	constraints := writer.RequiredVersions()
+	if assert.Len(t, constraints, 1) {
+		assert.Equal(t, ">= 0.13, < 2.0.0", constraints[0].Required.String())
+		assert.Equal(t, "main.tf", constraints[0].DeclRange.Filename)
+	}
+	assert.Empty(t, newTestModule(t, map[string]string{"main.tf": ""}).RequiredVersions())
+}
+
+func TestWriterCloudBlockDiags(t *testing.T) {
+	newTestModule(t, map[string]string{
+		"main.tf": `
+			terraform {
+				cloud {
+					organization = "org"
+				}
+			}
+		`,
+	})
+
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "main.tf", []byte("variable \"cloud\" {\n  cloud {}\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, diags := newWriter("", fs)
+	if assert.Len(t, diags, 1) {
+		assert.Equal(t, "Unsupported block type", diags[0].Summary)
+	}
+}
+
+func TestWriterRequiresVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   bool
+	}{
+		{constraint: "", expected: false},
+		{constraint: ">= 0.13", expected: true},
+		{constraint: ">= 0.12.20", expected: false},
+		{constraint: "> 0.13.0", expected: true},
+		{constraint: "~> 0.14.0", expected: true},
+		{constraint: "~> 0.12", expected: false},
+		{constraint: "0.13.5", expected: true},
+		{constraint: "= 0.12.31", expected: false},
+		{constraint: "< 1.0.0", expected: false},
+		{constraint: ">= 0.12, >= 0.13.1", expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.constraint, func(t *testing.T) {
+			config := ""
+			if tc.constraint != "" {
+				config = "terraform {\n  required_version = \"" + tc.constraint + "\"\n}\n"
+			}
+
+			writer := newTestModule(t, map[string]string{"main.tf": config})
+			assert.Equal(t, tc.expected, writer.requiresVersion(validationVersion))
+		})
+	}
+}
+
+func TestWriterValidationDiags(t *testing.T) {
+	newTestModule(t, map[string]string{
+		"main.tf": `
+			variable "environment" {
+				validation {
+					condition     = contains(["staging"], var.environment)
+					error_message = "The environment must be staging."
+				}
+			}
+		`,
+	})
+}
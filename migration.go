@@ -1,34 +1,240 @@
 package migrate
 
 import (
+	"sort"
+
 	"github.com/bendrucker/terraform-cloud-migrate/configwrite"
+	"github.com/bendrucker/terraform-cloud-migrate/state"
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
 	"github.com/hashicorp/hcl/v2"
 )
 
+// Step identifiers for selecting which steps run
+const (
+	StepRemoteBackend      = "remote-backend"
+	StepBackendConfig      = "backend-config"
+	StepWorkspaceLocals    = "workspace-locals"
+	StepTerraformWorkspace = "terraform-workspace"
+	StepTfvars             = "tfvars"
+	StepRemoteState        = "remote-state"
+)
+
+// StepIDs lists every step identifier in the order the steps run
+var StepIDs = []string{
+	StepRemoteBackend,
+	StepBackendConfig,
+	StepWorkspaceLocals,
+	StepTerraformWorkspace,
+	StepTfvars,
+	StepRemoteState,
+}
+
 func New(path string, config Config) (*Migration, hcl.Diagnostics) {
 	writer, diags := configwrite.New(path)
-	steps := configwrite.NewSteps(writer, configwrite.Steps{
-		&configwrite.RemoteBackend{Config: config.Backend},
-		&configwrite.TerraformWorkspace{Variable: config.WorkspaceVariable},
-		&configwrite.Tfvars{Filename: configwrite.TfvarsFilename},
-	})
+	if writer == nil {
+		return nil, diags
+	}
+
+	migration, mDiags := newMigration(writer, config)
+	return migration, append(diags, mDiags...)
+}
+
+func newMigration(writer *configwrite.Writer, config Config) (*Migration, hcl.Diagnostics) {
+	migration := &Migration{
+		writer:   writer,
+		cloud:    config.Backend.Cloud() && config.stepEnabled(StepRemoteBackend),
+		noFormat: config.NoFormat,
+	}
+	diags := writer.SetBackendConfig(config.BackendConfig)
+
+	add := func(id string, step configwrite.Step) {
+		if !config.stepEnabled(id) {
+			return
+		}
+		migration.steps = migration.steps.Append(step.WithWriter(writer))
+	}
+
+	add(StepRemoteBackend, &configwrite.RemoteBackend{Config: config.Backend})
+	backendConfig := &configwrite.BackendConfigFiles{
+		Convert:       config.ConvertBackendConfig,
+		RemoteBackend: config.Backend,
+	}
+	if config.stepEnabled(StepBackendConfig) {
+		migration.backendConfig = backendConfig
+	}
+	add(StepBackendConfig, backendConfig)
+	if config.ExtractWorkspaceLocals {
+		// lookups are replaced before terraform.workspace so that they are still recognized
+		step := &configwrite.WorkspaceLocals{
+			Variable: config.WorkspaceVariable,
+			Files:    config.WorkspaceTfvarsFiles,
+		}
+		if config.stepEnabled(StepWorkspaceLocals) {
+			migration.workspaceLocals = step
+		}
+		add(StepWorkspaceLocals, step)
+	}
+	workspace := &configwrite.TerraformWorkspace{
+		Variable:   config.WorkspaceVariable,
+		Workspaces: workspaceValues(writer, config),
+	}
+	if config.stepEnabled(StepTerraformWorkspace) {
+		migration.workspace = workspace
+	}
+	add(StepTerraformWorkspace, workspace)
+	if config.TfvarsUpload {
+		step := &configwrite.TfvarsVariables{
+			VarFiles:  config.VarFiles,
+			Sensitive: config.SensitiveVariables,
+		}
+		if config.stepEnabled(StepTfvars) {
+			migration.variables = step
+		}
+		add(StepTfvars, step)
+	} else {
+		add(StepTfvars, &configwrite.Tfvars{Filename: config.TfvarsFilename})
+	}
 
-	if config.ModulesDir != "" {
+	if config.ModulesDir != "" && config.stepEnabled(StepRemoteState) {
 		step := &configwrite.RemoteState{
 			RemoteBackend: config.Backend,
 			Path:          config.ModulesDir,
 		}
 		step.WithWriter(writer)
-		steps = steps.Append(step)
+		migration.remoteState = configwrite.Steps{step}
 	}
 
-	return &Migration{steps}, diags
+	return migration, diags
+}
+
+// workspaceValues returns the existing workspaces that the workspace variable may be set to, read from the local
+// backend's workspace directory unless they are configured. The default workspace is only included if it is mapped
+// to a Terraform Cloud workspace.
+func workspaceValues(writer *configwrite.Writer, config Config) []string {
+	workspaces := config.Workspaces
+	if local := writer.LocalBackend(); len(workspaces) == 0 && local != nil {
+		workspaces, _ = local.Workspaces()
+	}
+
+	values := make([]string, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		if _, mapped := config.Backend.Workspaces.Map[workspace]; workspace == state.DefaultWorkspace && !mapped {
+			continue
+		}
+		values = append(values, workspace)
+	}
+
+	sort.Strings(values)
+	return values
 }
 
 type Migration struct {
-	steps configwrite.Steps
+	writer          *configwrite.Writer
+	cloud           bool
+	steps           configwrite.Steps
+	remoteState     configwrite.Steps
+	backendConfig   *configwrite.BackendConfigFiles
+	variables       *configwrite.TfvarsVariables
+	workspaceLocals *configwrite.WorkspaceLocals
+	workspace       *configwrite.TerraformWorkspace
+	noFormat        bool
+}
+
+// Steps returns the steps that the migration will run
+func (m *Migration) Steps() configwrite.Steps {
+	return m.steps.Append(m.remoteState...)
+}
+
+// TerraformRequirements returns the version constraints that terraform must satisfy to initialize the module: its
+// required_version and the version that supports "cloud" blocks if the module has or will have one
+func (m *Migration) TerraformRequirements() []terraform.Requirement {
+	var requirements []terraform.Requirement
+	for _, constraint := range m.writer.RequiredVersions() {
+		subject := constraint.DeclRange
+		requirements = append(requirements, terraform.Requirement{
+			Constraints: constraint.Required,
+			Reason:      "required_version",
+			Subject:     &subject,
+		})
+	}
+
+	if m.cloud || m.writer.HasCloud() {
+		requirements = append(requirements, terraform.CloudRequirement)
+	}
+
+	return requirements
+}
+
+// BackendConfigFiles returns the backend config files that are converted to Terraform Cloud settings, which must
+// be passed to 'terraform init' after the changes are written
+func (m *Migration) BackendConfigFiles() []string {
+	if m.backendConfig == nil {
+		return nil
+	}
+	return m.backendConfig.ConvertedFiles()
 }
 
 func (m *Migration) Changes() (configwrite.Changes, hcl.Diagnostics) {
-	return m.steps.Changes()
+	changes, diags := m.Steps().Changes()
+	return changes, append(diags, m.format(changes)...)
+}
+
+// format disables formatting of the changes if configured, or reports files that will be reformatted
+func (m *Migration) format(changes configwrite.Changes) hcl.Diagnostics {
+	if m.noFormat {
+		changes.KeepFormatting()
+		return nil
+	}
+
+	return changes.Reformatted()
+}
+
+// Variables reads the values to upload as workspace variables. It returns nothing unless tfvars are uploaded.
+// Values extracted from workspace-keyed locals are set only for their workspace.
+func (m *Migration) Variables() ([]configwrite.Variable, hcl.Diagnostics) {
+	var variables []configwrite.Variable
+	var diags hcl.Diagnostics
+
+	if m.variables != nil {
+		vars, vDiags := m.variables.Variables()
+		variables = append(variables, vars...)
+		diags = append(diags, vDiags...)
+	}
+
+	if m.workspaceLocals != nil {
+		vars, vDiags := m.workspaceLocals.Variables()
+		variables = append(variables, vars...)
+		diags = append(diags, vDiags...)
+	}
+
+	return variables, diags
+}
+
+// WorkspaceOccurrences returns the location of each reference to terraform.workspace that will be replaced. References
+// in workspace-keyed lookups that are extracted to variables are not included.
+func (m *Migration) WorkspaceOccurrences() ([]hcl.Range, hcl.Diagnostics) {
+	if m.workspace == nil {
+		return nil, nil
+	}
+
+	ranges, diags := m.workspace.Occurrences()
+	if m.workspaceLocals == nil {
+		return ranges, diags
+	}
+
+	lookups, lDiags := m.workspaceLocals.Lookups()
+	diags = append(diags, lDiags...)
+
+	extracted := make(map[hcl.Range]bool, len(lookups))
+	for _, rng := range lookups {
+		extracted[rng] = true
+	}
+
+	occurrences := make([]hcl.Range, 0, len(ranges))
+	for _, rng := range ranges {
+		if !extracted[rng] {
+			occurrences = append(occurrences, rng)
+		}
+	}
+	return occurrences, diags
 }
@@ -0,0 +1,116 @@
+// Package state reads Terraform state from a module's existing backend so it can be uploaded to Terraform Cloud
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bendrucker/terraform-cloud-migrate/terraform"
+)
+
+// DefaultWorkspace is the workspace that every backend has
+const DefaultWorkspace = "default"
+
+// Source reads the state of a workspace. It returns nil if the workspace has no state.
+type Source interface {
+	State(workspace string) ([]byte, error)
+}
+
+// Defaults for the local backend's "path" and "workspace_dir" settings
+const (
+	DefaultPath         = "terraform.tfstate"
+	DefaultWorkspaceDir = "terraform.tfstate.d"
+)
+
+// Local reads the state files written by the local backend in a module directory
+type Local struct {
+	Dir string
+	// Path is the state file of the default workspace, relative to Dir. Defaults to terraform.tfstate.
+	Path string
+	// WorkspaceDir is the directory of the other workspaces' state, relative to Dir. Defaults to terraform.tfstate.d.
+	WorkspaceDir string
+}
+
+// statePath returns the path of a workspace's state file
+func (s *Local) statePath(workspace string) string {
+	if workspace == DefaultWorkspace {
+		return s.resolve(s.Path, DefaultPath)
+	}
+	return filepath.Join(s.workspaceDir(), workspace, DefaultPath)
+}
+
+func (s *Local) workspaceDir() string {
+	return s.resolve(s.WorkspaceDir, DefaultWorkspaceDir)
+}
+
+// resolve returns path relative to Dir, or fallback if path is empty
+func (s *Local) resolve(path, fallback string) string {
+	if path == "" {
+		path = fallback
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(s.Dir, path)
+}
+
+// Workspaces returns the names of the workspaces other than the default workspace, from the directories in the
+// workspace directory, sorted
+func (s *Local) Workspaces() ([]string, error) {
+	infos, err := ioutil.ReadDir(s.workspaceDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+// State reads the state file of a workspace
+func (s *Local) State(workspace string) ([]byte, error) {
+	b, err := ioutil.ReadFile(s.statePath(workspace))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+
+	return b, nil
+}
+
+// Pull reads state from any backend by running 'terraform state pull' in a module directory. The module
+// must be initialized with its existing backend.
+type Pull struct {
+	Terraform *terraform.Runner
+}
+
+// State pulls the state of a workspace, selected with TF_WORKSPACE
+func (s *Pull) State(workspace string) ([]byte, error) {
+	b, err := s.Terraform.StatePull(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull state for workspace '%s': %v", workspace, err)
+	}
+
+	return b, nil
+}
+
+var (
+	_ Source = (*Local)(nil)
+	_ Source = (*Pull)(nil)
+)
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"terraform.tfstate":                           `{"serial": 1}`,
+		"terraform.tfstate.d/prod/terraform.tfstate":  `{"serial": 2}`,
+		"terraform.tfstate.d/empty/terraform.tfstate": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &Local{Dir: dir}
+
+	for workspace, expected := range map[string][]byte{
+		DefaultWorkspace: []byte(`{"serial": 1}`),
+		"prod":           []byte(`{"serial": 2}`),
+		"empty":          nil,
+		"missing":        nil,
+	} {
+		state, err := source.State(workspace)
+		assert.NoError(t, err, workspace)
+		assert.Equal(t, expected, state, workspace)
+	}
+}
+
+func TestLocal_config(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"terraform.tfstate":                          `{"serial": 1}`,
+		"default.tfstate":                            `{"serial": 2}`,
+		"terraform.tfstate.d/prod/terraform.tfstate": `{"serial": 3}`,
+		"states/prod/terraform.tfstate":              `{"serial": 4}`,
+		"states/staging/terraform.tfstate":           `{"serial": 5}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &Local{Dir: dir, Path: "default.tfstate", WorkspaceDir: filepath.Join(dir, "states")}
+
+	for workspace, expected := range map[string][]byte{
+		DefaultWorkspace: []byte(`{"serial": 2}`),
+		"prod":           []byte(`{"serial": 4}`),
+		"staging":        []byte(`{"serial": 5}`),
+	} {
+		state, err := source.State(workspace)
+		assert.NoError(t, err, workspace)
+		assert.Equal(t, expected, state, workspace)
+	}
+
+	workspaces, err := source.Workspaces()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod", "staging"}, workspaces)
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Workspace is a workspace of a module's existing backend
+type Workspace struct {
+	Name string
+	// Empty is true if the workspace is known to have no state. Only workspaces of the local backend are checked.
+	Empty bool
+}
+
+// Workspaces are the workspaces of a module's existing backend
+type Workspaces struct {
+	// Current is the selected workspace, read from .terraform/environment
+	Current string
+	// All lists every workspace sorted by name, including the default workspace
+	All []Workspace
+}
+
+// Lister lists the workspaces of a backend, e.g. with 'terraform workspace list'
+type Lister func() ([]string, error)
+
+// Discover finds the workspaces of the module in dir. Workspaces are listed with list if it is set. Otherwise the
+// module uses the local backend, whose workspaces are read from local and checked for state. Local may be nil if list
+// is set.
+func Discover(dir string, local *Local, list Lister) (*Workspaces, error) {
+	current, err := CurrentWorkspace(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := &Workspaces{Current: current}
+	names := map[string]bool{DefaultWorkspace: true, current: true}
+
+	if list != nil {
+		listed, err := list()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range listed {
+			names[name] = true
+		}
+	} else {
+		listed, err := local.Workspaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range listed {
+			names[name] = true
+		}
+	}
+
+	for name := range names {
+		workspace := Workspace{Name: name}
+		if list == nil {
+			b, err := local.State(name)
+			if err != nil {
+				return nil, err
+			}
+			workspace.Empty = b == nil
+		}
+		workspaces.All = append(workspaces.All, workspace)
+	}
+
+	sort.Slice(workspaces.All, func(i, j int) bool {
+		return workspaces.All[i].Name < workspaces.All[j].Name
+	})
+
+	return workspaces, nil
+}
+
+// Names returns the name of every workspace
+func (w *Workspaces) Names() []string {
+	names := make([]string, len(w.All))
+	for i, workspace := range w.All {
+		names[i] = workspace.Name
+	}
+	return names
+}
+
+// CurrentWorkspace reads the selected workspace from .terraform/environment
+func CurrentWorkspace(dir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ".terraform", "environment"))
+	if os.IsNotExist(err) {
+		return DefaultWorkspace, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if name := strings.TrimSpace(string(b)); name != "" {
+		return name, nil
+	}
+	return DefaultWorkspace, nil
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testModuleDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestDiscover_local(t *testing.T) {
+	dir := testModuleDir(t, map[string]string{
+		".terraform/environment":                      "prod\n",
+		"terraform.tfstate.d/prod/terraform.tfstate":  `{"serial": 2}`,
+		"terraform.tfstate.d/empty/terraform.tfstate": "",
+	})
+	defer os.RemoveAll(dir)
+
+	workspaces, err := Discover(dir, &Local{Dir: dir}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &Workspaces{
+		Current: "prod",
+		All: []Workspace{
+			{Name: DefaultWorkspace, Empty: true},
+			{Name: "empty", Empty: true},
+			{Name: "prod"},
+		},
+	}, workspaces)
+	assert.Equal(t, []string{DefaultWorkspace, "empty", "prod"}, workspaces.Names())
+}
+
+func TestDiscover_localConfig(t *testing.T) {
+	dir := testModuleDir(t, map[string]string{
+		"state/default.tfstate":               `{"serial": 1}`,
+		"states/prod/terraform.tfstate":       `{"serial": 2}`,
+		"terraform.tfstate.d/stale/terraform": `{"serial": 3}`,
+	})
+	defer os.RemoveAll(dir)
+
+	workspaces, err := Discover(dir, &Local{Dir: dir, Path: "state/default.tfstate", WorkspaceDir: "states"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Workspace{
+		{Name: DefaultWorkspace},
+		{Name: "prod"},
+	}, workspaces.All)
+}
+
+func TestDiscover_list(t *testing.T) {
+	dir := testModuleDir(t, map[string]string{
+		".terraform/environment": "staging",
+	})
+	defer os.RemoveAll(dir)
+
+	workspaces, err := Discover(dir, nil, func() ([]string, error) {
+		return []string{DefaultWorkspace, "staging", "production"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Workspaces{
+		Current: "staging",
+		All: []Workspace{
+			{Name: DefaultWorkspace},
+			{Name: "production"},
+			{Name: "staging"},
+		},
+	}, workspaces)
+
+	_, err = Discover(dir, nil, func() ([]string, error) {
+		return nil, errors.New("backend not initialized")
+	})
+	assert.EqualError(t, err, "backend not initialized")
+}
+
+func TestCurrentWorkspace(t *testing.T) {
+	dir := testModuleDir(t, map[string]string{})
+	defer os.RemoveAll(dir)
+
+	current, err := CurrentWorkspace(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultWorkspace, current)
+}
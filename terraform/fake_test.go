@@ -0,0 +1,71 @@
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeScript is a terraform binary that records each call and prints canned output for each command
+const fakeScript = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$TF_WORKSPACE|$*" >> "$dir/calls"
+[ -f "$dir/$1.stdout" ] && cat "$dir/$1.stdout"
+[ -f "$dir/$1.stderr" ] && cat "$dir/$1.stderr" >&2
+exit $(cat "$dir/$1.exit" 2>/dev/null || echo 0)
+`
+
+// fakeTerraform is a fake terraform binary that is first on PATH
+type fakeTerraform struct {
+	dir  string
+	path string
+}
+
+func newFakeTerraform(t *testing.T) *fakeTerraform {
+	dir, err := ioutil.TempDir("", "terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "terraform"), []byte(fakeScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeTerraform{dir: dir, path: os.Getenv("PATH")}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+f.path)
+	return f
+}
+
+func (f *fakeTerraform) Close() {
+	os.Setenv("PATH", f.path)
+	os.RemoveAll(f.dir)
+}
+
+// respond sets the output and exit status of a command
+func (f *fakeTerraform) respond(t *testing.T, command, stdout, stderr string, exit int) {
+	files := map[string]string{
+		command + ".stdout": stdout,
+		command + ".stderr": stderr,
+		command + ".exit":   fmt.Sprint(exit),
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(f.dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// calls returns each call as TF_WORKSPACE followed by "|" and the arguments
+func (f *fakeTerraform) calls(t *testing.T) []string {
+	b, err := ioutil.ReadFile(filepath.Join(f.dir, "calls"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
@@ -0,0 +1,135 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// InitOptions configures 'terraform init'
+type InitOptions struct {
+	// ForceCopy copies state to a new backend without prompting for confirmation
+	ForceCopy bool
+	// NoInput fails instead of prompting for values that are not set
+	NoInput bool
+	// Reconfigure ignores the saved backend configuration instead of migrating state from it
+	Reconfigure bool
+	// BackendConfig values are passed with -backend-config, each a file or a key=value pair
+	BackendConfig []string
+	// Workspace selects a workspace with TF_WORKSPACE, which is needed without input when the new backend
+	// does not have the current workspace
+	Workspace string
+	// Source is the state of the current workspace in the previous backend. If set, the new backend's state is
+	// pulled after init succeeds to check whether it was copied.
+	Source *StateSnapshot
+}
+
+func (o InitOptions) args() []string {
+	args := []string{"init"}
+	if o.ForceCopy {
+		args = append(args, "-force-copy")
+	}
+	if o.NoInput {
+		args = append(args, "-input=false")
+	}
+	if o.Reconfigure {
+		args = append(args, "-reconfigure")
+	}
+	for _, value := range o.BackendConfig {
+		args = append(args, "-backend-config="+value)
+	}
+	return args
+}
+
+func (o InitOptions) env() []string {
+	if o.Workspace == "" {
+		return nil
+	}
+	return []string{"TF_WORKSPACE=" + o.Workspace}
+}
+
+// InitResult is the outcome of 'terraform init' parsed from its output
+type InitResult struct {
+	*Result
+	// Initialized is true if terraform reported that initialization succeeded
+	Initialized bool
+	// StateMigrated is true if the new backend has the Source state after init
+	StateMigrated bool
+}
+
+// initializedMessages are printed when initialization succeeds
+var initializedMessages = []string{
+	"Terraform has been successfully initialized",
+	"Terraform Cloud has been successfully initialized",
+}
+
+// Init runs 'terraform init'. A failed init returns an *ExitError along with its result. If the state of a successful
+// init cannot be checked, the error is returned with a result that has a zero ExitCode.
+func (r *Runner) Init(options InitOptions) (*InitResult, error) {
+	result, err := r.Run(options.env(), options.args()...)
+	if result == nil {
+		return nil, err
+	}
+
+	init := parseInit(result)
+	if err != nil || options.Source == nil {
+		return init, err
+	}
+
+	// the state is only captured, not printed
+	quiet := &Runner{Bin: r.Bin, Dir: r.Dir}
+	pulled, err := quiet.Run(options.env(), "state", "pull")
+	if err != nil {
+		return init, fmt.Errorf("failed to check the copied state: %v", err)
+	}
+
+	snapshot, err := ParseStateSnapshot([]byte(pulled.Stdout))
+	if err != nil {
+		return init, fmt.Errorf("failed to check the copied state: %v", err)
+	}
+
+	init.StateMigrated = options.Source.copiedTo(snapshot)
+	return init, nil
+}
+
+func parseInit(result *Result) *InitResult {
+	return &InitResult{
+		Result:      result,
+		Initialized: result.ExitCode == 0 && containsAny(result.Output(), initializedMessages),
+	}
+}
+
+// StateSnapshot identifies a state by its lineage and serial, which Terraform keeps when it copies state to a new
+// backend
+type StateSnapshot struct {
+	Lineage string `json:"lineage"`
+	Serial  int64  `json:"serial"`
+}
+
+// ParseStateSnapshot reads the lineage and serial of a state file. It returns nil if the state is empty.
+func ParseStateSnapshot(state []byte) (*StateSnapshot, error) {
+	if strings.TrimSpace(string(state)) == "" {
+		return nil, nil
+	}
+
+	var snapshot StateSnapshot
+	if err := json.Unmarshal(state, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// copiedTo returns whether target is a copy of the snapshot. Terraform increments the serial if the target already
+// had a different state.
+func (s *StateSnapshot) copiedTo(target *StateSnapshot) bool {
+	return target != nil && target.Lineage == s.Lineage && target.Serial >= s.Serial
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerInit(t *testing.T) {
+	source := &StateSnapshot{Lineage: "a1b2", Serial: 3}
+
+	tests := []struct {
+		name     string
+		options  InitOptions
+		stdout   string
+		pulled   string
+		exit     int
+		calls    []string
+		expected InitResult
+		err      string
+	}{
+		{
+			name: "migrated",
+			options: InitOptions{
+				ForceCopy: true,
+				NoInput:   true,
+				Workspace: "staging",
+				Source:    source,
+			},
+			stdout: "Initializing the backend...\nTerraform detected that the backend type changed from \"s3\" to \"remote\".\n\nSuccessfully configured the backend \"remote\"!\n\nTerraform has been successfully initialized!\n",
+			pulled: `{"version": 4, "serial": 3, "lineage": "a1b2"}`,
+			calls:  []string{"staging|init -force-copy -input=false", "staging|state pull"},
+			expected: InitResult{
+				Initialized:   true,
+				StateMigrated: true,
+			},
+		},
+		{
+			name: "cloud",
+			options: InitOptions{
+				ForceCopy: true,
+				Source:    source,
+			},
+			stdout: "Initializing Terraform Cloud...\nMigrating from backend \"local\" to Terraform Cloud.\n\nTerraform Cloud has been successfully initialized!\n",
+			pulled: `{"version": 4, "serial": 4, "lineage": "a1b2"}`,
+			calls:  []string{"|init -force-copy", "|state pull"},
+			expected: InitResult{
+				Initialized:   true,
+				StateMigrated: true,
+			},
+		},
+		{
+			name:    "declined",
+			options: InitOptions{Source: source},
+			stdout:  "Initializing the backend...\nTerraform detected that the backend type changed from \"s3\" to \"remote\".\n\nDo you want to copy existing state to the new backend?\n  Enter a value: no\n\nSuccessfully configured the backend \"remote\"!\n\nTerraform has been successfully initialized!\n",
+			calls:   []string{"|init", "|state pull"},
+			expected: InitResult{
+				Initialized: true,
+			},
+		},
+		{
+			name:    "existing state",
+			options: InitOptions{Source: source},
+			stdout:  "Initializing the backend...\n\nDo you want to copy existing state to the new backend?\n  Enter a value: no\n\nTerraform has been successfully initialized!\n",
+			pulled:  `{"version": 4, "serial": 8, "lineage": "c3d4"}`,
+			calls:   []string{"|init", "|state pull"},
+			expected: InitResult{
+				Initialized: true,
+			},
+		},
+		{
+			name: "unchanged",
+			options: InitOptions{
+				Reconfigure:   true,
+				BackendConfig: []string{"backend.hcl", "key=app"},
+			},
+			stdout: "Initializing the backend...\n\nTerraform has been successfully initialized!\n",
+			calls:  []string{"|init -reconfigure -backend-config=backend.hcl -backend-config=key=app"},
+			expected: InitResult{
+				Initialized: true,
+			},
+		},
+		{
+			name:    "failed",
+			options: InitOptions{NoInput: true, Source: source},
+			stdout:  "Initializing the backend...\n\nError: Error asking for state migration action: input disabled\n",
+			exit:    1,
+			calls:   []string{"|init -input=false"},
+			err:     "terraform init exited with status 1: Error asking for state migration action: input disabled",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeTerraform(t)
+			defer fake.Close()
+
+			fake.respond(t, "init", test.stdout, "", test.exit)
+			fake.respond(t, "state", test.pulled, "", 0)
+
+			result, err := New(fake.dir).Init(test.options)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.exit, result.ExitCode)
+			assert.Equal(t, test.expected.Initialized, result.Initialized)
+			assert.Equal(t, test.expected.StateMigrated, result.StateMigrated)
+			assert.Equal(t, test.calls, fake.calls(t))
+		})
+	}
+}
+
+func TestParseStateSnapshot(t *testing.T) {
+	snapshot, err := ParseStateSnapshot([]byte(`{"version": 4, "serial": 3, "lineage": "a1b2"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &StateSnapshot{Lineage: "a1b2", Serial: 3}, snapshot)
+
+	snapshot, err = ParseStateSnapshot([]byte("\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	_, err = ParseStateSnapshot([]byte("{"))
+	assert.Error(t, err)
+}
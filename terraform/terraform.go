@@ -0,0 +1,156 @@
+// Package terraform runs the Terraform CLI in a module directory and parses its output
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Runner runs terraform commands in a module directory
+type Runner struct {
+	// Bin is the terraform binary. If empty, terraform is found on PATH.
+	Bin string
+	Dir string
+
+	// Stdin is connected to commands that may prompt for input. Commands do not read input if it is nil.
+	Stdin io.Reader
+	// Stdout and Stderr receive the output of commands as it is written, in addition to it being captured
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New creates a runner for the module in dir that uses terraform from PATH and only captures output
+func New(dir string) *Runner {
+	return &Runner{Dir: dir}
+}
+
+// Result is the captured output of a command
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Output returns stdout and stderr without terminal color codes
+func (r *Result) Output() string {
+	return stripColor(r.Stdout + r.Stderr)
+}
+
+// ExitError is returned when a command exits with a non-zero status
+type ExitError struct {
+	Command string
+	Result  *Result
+}
+
+func (e *ExitError) Error() string {
+	message := errorSummary(e.Result.Output())
+	if message == "" {
+		message = strings.TrimSpace(stripColor(e.Result.Stderr))
+	}
+	return fmt.Sprintf("terraform %s exited with status %d: %s", e.Command, e.Result.ExitCode, message)
+}
+
+// bin returns the terraform binary
+func (r *Runner) bin() string {
+	if r.Bin != "" {
+		return r.Bin
+	}
+	return "terraform"
+}
+
+// Run runs terraform with args. Variables in env are set in addition to the current environment. Commands that exit
+// with a non-zero status return an *ExitError along with their result.
+func (r *Runner) Run(env []string, args ...string) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(r.bin(), args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = r.Stdin
+	cmd.Stdout = tee(&stdout, r.Stdout)
+	cmd.Stderr = tee(&stderr, r.Stderr)
+
+	err := cmd.Run()
+	result := &Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		return result, &ExitError{Command: args[0], Result: result}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v", r.bin(), err)
+	}
+
+	return result, nil
+}
+
+// StatePull reads the state of a workspace, selected with TF_WORKSPACE. It returns nil if the workspace has no state.
+func (r *Runner) StatePull(workspace string) ([]byte, error) {
+	result, err := r.Run([]string{"TF_WORKSPACE=" + workspace}, "state", "pull")
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(result.Stdout) == "" {
+		return nil, nil
+	}
+
+	return []byte(result.Stdout), nil
+}
+
+// WorkspaceList lists the workspaces of the module's current backend
+func (r *Runner) WorkspaceList() ([]string, error) {
+	result, err := r.Run(nil, "workspace", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWorkspaceList(result.Stdout), nil
+}
+
+// parseWorkspaceList parses the output of 'terraform workspace list', where the current workspace is marked with "*"
+func parseWorkspaceList(output string) []string {
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "*"))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var colorPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripColor(s string) string {
+	return colorPattern.ReplaceAllString(s, "")
+}
+
+// errorSummary returns the first "Error:" line of terraform's output, without the prefix
+func errorSummary(output string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimLeft(scanner.Text(), "│╷╵ "))
+		if strings.HasPrefix(line, "Error: ") {
+			return strings.TrimPrefix(line, "Error: ")
+		}
+	}
+	return ""
+}
+
+func tee(buf *bytes.Buffer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
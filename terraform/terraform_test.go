@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerRun(t *testing.T) {
+	fake := newFakeTerraform(t)
+	defer fake.Close()
+
+	fake.respond(t, "plan", "planning\n", "\x1b[31m\nError: \x1b[0m\x1b[1mNo configuration files\x1b[0m\n", 1)
+
+	var stdout bytes.Buffer
+	runner := &Runner{Dir: fake.dir, Stdout: &stdout}
+
+	result, err := runner.Run([]string{"TF_WORKSPACE=staging"}, "plan", "-input=false")
+	assert.EqualError(t, err, "terraform plan exited with status 1: No configuration files")
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "planning\n", result.Stdout)
+	assert.Equal(t, "planning\n", stdout.String())
+	assert.Equal(t, []string{"staging|plan -input=false"}, fake.calls(t))
+}
+
+func TestRunnerRun_bin(t *testing.T) {
+	_, err := (&Runner{Bin: "/nonexistent/terraform"}).Run(nil, "version")
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "exited")
+}
+
+func TestRunnerStatePull(t *testing.T) {
+	fake := newFakeTerraform(t)
+	defer fake.Close()
+
+	runner := New(fake.dir)
+
+	fake.respond(t, "state", `{"serial": 1}`, "", 0)
+	state, err := runner.StatePull("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"serial": 1}`), state)
+
+	fake.respond(t, "state", "\n", "", 0)
+	state, err = runner.StatePull("empty")
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	fake.respond(t, "state", "", "Error: Failed to load state\n", 1)
+	_, err = runner.StatePull("broken")
+	assert.EqualError(t, err, "terraform state exited with status 1: Failed to load state")
+
+	assert.Equal(t, []string{
+		"prod|state pull",
+		"empty|state pull",
+		"broken|state pull",
+	}, fake.calls(t))
+}
+
+func TestRunnerWorkspaceList(t *testing.T) {
+	fake := newFakeTerraform(t)
+	defer fake.Close()
+
+	fake.respond(t, "workspace", "  default\n* staging\n  production\n\n", "", 0)
+
+	workspaces, err := New(fake.dir).WorkspaceList()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "staging", "production"}, workspaces)
+	assert.Equal(t, []string{"|workspace list"}, fake.calls(t))
+}
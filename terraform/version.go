@@ -0,0 +1,113 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// CloudVersion is the first version of Terraform that supports "cloud" blocks
+var CloudVersion = version.Must(version.NewVersion("1.1.0"))
+
+// Version is the version of a terraform binary
+type Version struct {
+	Version  *version.Version
+	Platform string
+}
+
+func (v *Version) String() string {
+	s := "v" + v.Version.String()
+	if v.Platform != "" {
+		s += " on " + v.Platform
+	}
+	return s
+}
+
+// Version runs 'terraform version'. Output from Terraform 0.12 and earlier, which do not support -json, is parsed
+// from text.
+func (r *Runner) Version() (*Version, error) {
+	result, err := r.Run(nil, "version", "-json")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVersion(result.Stdout)
+}
+
+// versionPattern matches the first line of 'terraform version' text output
+var versionPattern = regexp.MustCompile(`^Terraform v(\S+)`)
+
+func parseVersion(output string) (*Version, error) {
+	output = strings.TrimSpace(output)
+
+	var raw struct {
+		Version  string `json:"terraform_version"`
+		Platform string `json:"platform"`
+	}
+	if strings.HasPrefix(output, "{") {
+		if err := json.Unmarshal([]byte(output), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse terraform version: %v", err)
+		}
+	} else if match := versionPattern.FindStringSubmatch(output); match != nil {
+		raw.Version = match[1]
+	}
+
+	if raw.Version == "" {
+		return nil, fmt.Errorf("failed to parse terraform version from output: %q", output)
+	}
+
+	v, err := version.NewVersion(raw.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse terraform version: %v", err)
+	}
+
+	return &Version{Version: v, Platform: raw.Platform}, nil
+}
+
+// Requirement is a version constraint that terraform must satisfy to migrate a module
+type Requirement struct {
+	Constraints version.Constraints
+	// Reason describes what needs the constraint, e.g. "required_version"
+	Reason string
+	// Subject is the configuration that declares the constraint, if any
+	Subject *hcl.Range
+}
+
+// CloudRequirement is the version required to use "cloud" blocks
+var CloudRequirement = Requirement{
+	Constraints: mustConstraints(">= " + CloudVersion.String()),
+	Reason:      `"cloud" blocks`,
+}
+
+// CheckVersion returns an error for each requirement that the version does not satisfy
+func CheckVersion(v *Version, requirements []Requirement) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, requirement := range requirements {
+		if requirement.Constraints.Check(v.Version) {
+			continue
+		}
+
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported Terraform version",
+			Detail: fmt.Sprintf(
+				"Terraform v%s does not satisfy %s, which is required by %s. Use --terraform-bin to select a different terraform binary.",
+				v.Version, requirement.Constraints, requirement.Reason,
+			),
+			Subject: requirement.Subject,
+		})
+	}
+	return diags
+}
+
+func mustConstraints(constraint string) version.Constraints {
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		panic(err)
+	}
+	return constraints
+}
@@ -0,0 +1,64 @@
+package terraform
+
+import (
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerVersion(t *testing.T) {
+	fake := newFakeTerraform(t)
+	defer fake.Close()
+
+	fake.respond(t, "version", `{"terraform_version": "1.1.7", "platform": "linux_amd64", "provider_selections": {}}`, "", 0)
+
+	v, err := New(fake.dir).Version()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.7", v.Version.String())
+	assert.Equal(t, "v1.1.7 on linux_amd64", v.String())
+	assert.Equal(t, []string{"|version -json"}, fake.calls(t))
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := parseVersion("Terraform v0.12.24\n\nYour version of Terraform is out of date!\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.12.24", v.Version.String())
+	assert.Equal(t, "", v.Platform)
+
+	_, err = parseVersion("unknown command\n")
+	assert.EqualError(t, err, `failed to parse terraform version from output: "unknown command"`)
+
+	_, err = parseVersion(`{"terraform_version": 1}`)
+	assert.Error(t, err)
+}
+
+func TestCheckVersion(t *testing.T) {
+	subject := &hcl.Range{Filename: "main.tf"}
+	requirements := []Requirement{
+		{Constraints: mustConstraints("~> 0.14.0"), Reason: "required_version", Subject: subject},
+		CloudRequirement,
+	}
+
+	v := &Version{Version: version.Must(version.NewVersion("0.14.11"))}
+	assert.Equal(t, hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported Terraform version",
+			Detail:   `Terraform v0.14.11 does not satisfy >= 1.1.0, which is required by "cloud" blocks. Use --terraform-bin to select a different terraform binary.`,
+		},
+	}, CheckVersion(v, requirements))
+
+	v = &Version{Version: version.Must(version.NewVersion("1.1.0"))}
+	assert.Equal(t, hcl.Diagnostics{
+		&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported Terraform version",
+			Detail:   `Terraform v1.1.0 does not satisfy ~> 0.14.0, which is required by required_version. Use --terraform-bin to select a different terraform binary.`,
+			Subject:  subject,
+		},
+	}, CheckVersion(v, requirements))
+
+	assert.Empty(t, CheckVersion(v, requirements[1:]))
+}
@@ -0,0 +1,201 @@
+// Package tfe is a minimal client for the Terraform Cloud and Terraform Enterprise API
+package tfe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultHostname is the hostname of Terraform Cloud
+	DefaultHostname = "app.terraform.io"
+
+	apiPath     = "/api/v2"
+	contentType = "application/vnd.api+json"
+)
+
+// ErrNotFound is returned when the API responds with 404, including when the token cannot access a resource
+var ErrNotFound = errors.New("resource not found")
+
+// NewClient creates a client for the API at hostname, authenticated with token
+func NewClient(hostname, token string) *Client {
+	return &Client{
+		Address:    "https://" + hostname,
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Client calls the Terraform Cloud API
+type Client struct {
+	// Address is the scheme and host of the API, e.g. https://app.terraform.io
+	Address    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+type document struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		Pagination *pagination `json:"pagination"`
+	} `json:"meta"`
+}
+
+// pagination is the page metadata of a list response. NextPage is nil on the last page.
+type pagination struct {
+	NextPage *int `json:"next-page"`
+}
+
+// pageSize is the number of resources requested per page, which is the maximum that the API allows
+const pageSize = 100
+
+type resource struct {
+	ID            string                  `json:"id,omitempty"`
+	Type          string                  `json:"type"`
+	Attributes    interface{}             `json:"attributes,omitempty"`
+	Relationships map[string]relationship `json:"relationships,omitempty"`
+}
+
+type relationship struct {
+	Data resource `json:"data"`
+}
+
+type errorDocument struct {
+	Errors []struct {
+		Status string `json:"status"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// data wraps a resource in a JSON:API document
+func data(v interface{}) interface{} {
+	return map[string]interface{}{"data": v}
+}
+
+// do sends a request with an optional JSON body and decodes the "data" of the response into out
+func (c *Client) do(method, path string, body interface{}, out interface{}) error {
+	doc, err := c.request(method, path, body)
+	if err != nil || out == nil || doc == nil {
+		return err
+	}
+
+	return json.Unmarshal(doc.Data, out)
+}
+
+// list requests every page of a collection and calls each with the "data" of every page, in order
+func (c *Client) list(path string, each func(data json.RawMessage) error) error {
+	page := 1
+	for {
+		query := url.Values{
+			"page[number]": []string{fmt.Sprint(page)},
+			"page[size]":   []string{fmt.Sprint(pageSize)},
+		}
+
+		doc, err := c.request(http.MethodGet, path+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			return nil
+		}
+
+		if err := each(doc.Data); err != nil {
+			return err
+		}
+
+		if doc.Meta.Pagination == nil || doc.Meta.Pagination.NextPage == nil {
+			return nil
+		}
+		page = *doc.Meta.Pagination.NextPage
+	}
+}
+
+// request sends a request with an optional JSON body and decodes the response document, which is nil if the
+// response has no body
+func (c *Client) request(method, path string, body interface{}) (*document, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.Address+apiPath+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if res.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		var doc errorDocument
+		if json.Unmarshal(b, &doc) == nil {
+			for _, e := range doc.Errors {
+				msg := e.Title
+				if e.Detail != "" {
+					msg = fmt.Sprintf("%s: %s", e.Title, e.Detail)
+				}
+				apiErr.Errors = append(apiErr.Errors, msg)
+			}
+		}
+		return nil, apiErr
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func escape(s string) string {
+	return url.PathEscape(s)
+}
@@ -0,0 +1,63 @@
+package tfe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenEnv is the environment variable that overrides the token from the CLI configuration
+const TokenEnv = "TFE_TOKEN"
+
+type credentialsFile struct {
+	Credentials map[string]struct {
+		Token string `json:"token"`
+	} `json:"credentials"`
+}
+
+// Token finds an API token for hostname. It checks TFE_TOKEN, TF_TOKEN_<hostname>, and then the
+// credentials that 'terraform login' saves in ~/.terraform.d/credentials.tfrc.json.
+func Token(hostname string) (string, error) {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token, nil
+	}
+
+	if token := os.Getenv(hostTokenEnv(hostname)); token != "" {
+		return token, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return credentialsToken(filepath.Join(home, ".terraform.d", "credentials.tfrc.json"), hostname)
+}
+
+func credentialsToken(path, hostname string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err == nil {
+		var file credentialsFile
+		if err := json.Unmarshal(b, &file); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		if creds, ok := file.Credentials[hostname]; ok && creds.Token != "" {
+			return creds.Token, nil
+		}
+	}
+
+	return "", fmt.Errorf("no API token found for %s: set %s or run 'terraform login %s'", hostname, TokenEnv, hostname)
+}
+
+// hostTokenEnv returns the variable Terraform reads a host's token from, e.g. TF_TOKEN_app_terraform_io
+func hostTokenEnv(hostname string) string {
+	return "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(hostname)
+}
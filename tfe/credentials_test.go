@@ -0,0 +1,38 @@
+package tfe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "credentials.tfrc.json")
+	if err := ioutil.WriteFile(path, []byte(`{"credentials": {"app.terraform.io": {"token": "secret"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := credentialsToken(path, "app.terraform.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", token)
+
+	_, err = credentialsToken(path, "tfe.example.com")
+	assert.EqualError(t, err, "no API token found for tfe.example.com: set TFE_TOKEN or run 'terraform login tfe.example.com'")
+
+	_, err = credentialsToken(filepath.Join(dir, "missing.json"), "app.terraform.io")
+	assert.Error(t, err)
+}
+
+func TestHostTokenEnv(t *testing.T) {
+	assert.Equal(t, "TF_TOKEN_app_terraform_io", hostTokenEnv("app.terraform.io"))
+	assert.Equal(t, "TF_TOKEN_my__tfe_example_com", hostTokenEnv("my-tfe.example.com"))
+}
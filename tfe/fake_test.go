@@ -0,0 +1,340 @@
+package tfe
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "test-token"
+
+// fakeServer is an in-memory implementation of the parts of the API used by the client
+type fakeServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	workspaces map[string]*workspaceResource
+	variables  map[string][]*variableResource
+	locked     map[string]bool
+	states     map[string][]*fakeStateVersion
+	requests   []string
+	nextID     int
+	// pageSize limits the size of list pages, if set
+	pageSize int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	f := &fakeServer{
+		workspaces: make(map[string]*workspaceResource),
+		variables:  make(map[string][]*variableResource),
+		locked:     make(map[string]bool),
+		states:     make(map[string][]*fakeStateVersion),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) client() *Client {
+	c := NewClient("", testToken)
+	c.Address = f.URL
+	c.HTTPClient = f.Client()
+	return c
+}
+
+func (f *fakeServer) id(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%d", prefix, f.nextID)
+}
+
+func (f *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		writeErrors(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiPath+"/"), "/")
+
+	switch {
+	case len(parts) == 3 && parts[0] == "organizations" && parts[2] == "workspaces" && r.Method == http.MethodPost:
+		var doc struct {
+			Data workspaceResource `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		key := parts[1] + "/" + doc.Data.Attributes.Name
+		if _, ok := f.workspaces[key]; ok {
+			writeErrors(w, http.StatusUnprocessableEntity, "Name has already been taken")
+			return
+		}
+
+		if !validAgentPool(doc.Data.Attributes) {
+			writeErrors(w, http.StatusUnprocessableEntity, "Agent pool must be set when execution mode is agent")
+			return
+		}
+
+		ws := &workspaceResource{ID: f.id("ws"), Attributes: doc.Data.Attributes}
+		f.workspaces[key] = ws
+		writeData(w, http.StatusCreated, ws)
+	case len(parts) == 4 && parts[0] == "organizations" && parts[2] == "workspaces":
+		ws, ok := f.workspaces[parts[1]+"/"+parts[3]]
+		if !ok {
+			writeErrors(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeData(w, http.StatusOK, ws)
+		case http.MethodPatch:
+			var doc struct {
+				Data workspaceResource `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+				writeErrors(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			attributes := ws.Attributes
+			mergeWorkspace(&attributes, doc.Data.Attributes)
+			if !validAgentPool(attributes) {
+				writeErrors(w, http.StatusUnprocessableEntity, "Agent pool must be set when execution mode is agent")
+				return
+			}
+			ws.Attributes = attributes
+			writeData(w, http.StatusOK, ws)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case len(parts) >= 3 && parts[0] == "workspaces" && parts[2] == "vars":
+		f.handleVariables(w, r, parts[1], parts[3:])
+	case len(parts) == 4 && parts[0] == "workspaces" && parts[2] == "actions" && r.Method == http.MethodPost:
+		f.handleLock(w, parts[1], parts[3] == "lock")
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "state-versions" && r.Method == http.MethodPost:
+		f.handleCreateStateVersion(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "current-state-version" && r.Method == http.MethodGet:
+		versions := f.states[parts[1]]
+		if len(versions) == 0 {
+			writeErrors(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeData(w, http.StatusOK, versions[len(versions)-1].resource)
+	default:
+		writeErrors(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (f *fakeServer) workspaceByID(id string) *workspaceResource {
+	for _, ws := range f.workspaces {
+		if ws.ID == id {
+			return ws
+		}
+	}
+	return nil
+}
+
+func (f *fakeServer) handleVariables(w http.ResponseWriter, r *http.Request, workspaceID string, rest []string) {
+	if f.workspaceByID(workspaceID) == nil {
+		writeErrors(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var doc struct {
+		Data variableResource `json:"data"`
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		f.writePage(w, r, f.variables[workspaceID])
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		for _, v := range f.variables[workspaceID] {
+			if v.Attributes.Key == doc.Data.Attributes.Key && v.Attributes.Category == doc.Data.Attributes.Category {
+				writeErrors(w, http.StatusUnprocessableEntity, "Key has already been taken")
+				return
+			}
+		}
+
+		v := &variableResource{ID: f.id("var"), Attributes: doc.Data.Attributes}
+		f.variables[workspaceID] = append(f.variables[workspaceID], v)
+		writeData(w, http.StatusCreated, v)
+	case len(rest) == 1 && r.Method == http.MethodPatch:
+		for _, v := range f.variables[workspaceID] {
+			if v.ID == rest[0] {
+				v.Attributes = doc.Data.Attributes
+				writeData(w, http.StatusOK, v)
+				return
+			}
+		}
+		writeErrors(w, http.StatusNotFound, "not found")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func mergeWorkspace(ws *workspaceAttributes, update workspaceAttributes) {
+	if update.TerraformVersion != "" {
+		ws.TerraformVersion = update.TerraformVersion
+	}
+	if update.ExecutionMode != "" {
+		ws.ExecutionMode = update.ExecutionMode
+		ws.AgentPoolID = ""
+	}
+	if update.AgentPoolID != "" {
+		ws.AgentPoolID = update.AgentPoolID
+	}
+	if update.WorkingDirectory != "" {
+		ws.WorkingDirectory = update.WorkingDirectory
+	}
+	if update.AutoApply != nil {
+		ws.AutoApply = update.AutoApply
+	}
+	if update.TagNames != nil {
+		ws.TagNames = update.TagNames
+	}
+}
+
+// validAgentPool checks that an agent pool is set only with the agent execution mode
+func validAgentPool(ws workspaceAttributes) bool {
+	return (ws.ExecutionMode == ExecutionModeAgent) == (ws.AgentPoolID != "")
+}
+
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// writePage writes the page of variables requested with page[number] and page[size], with pagination metadata
+func (f *fakeServer) writePage(w http.ResponseWriter, r *http.Request, variables []*variableResource) {
+	number, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+	if number < 1 {
+		number = 1
+	}
+	size, _ := strconv.Atoi(r.URL.Query().Get("page[size]"))
+	if size < 1 || (f.pageSize > 0 && size > f.pageSize) {
+		size = f.pageSize
+	}
+	if size < 1 {
+		size = 20
+	}
+
+	start := (number - 1) * size
+	if start > len(variables) {
+		start = len(variables)
+	}
+	end := start + size
+	if end > len(variables) {
+		end = len(variables)
+	}
+
+	var next *int
+	if end < len(variables) {
+		n := number + 1
+		next = &n
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": variables[start:end],
+		"meta": map[string]interface{}{
+			"pagination": map[string]interface{}{
+				"current-page": number,
+				"next-page":    next,
+			},
+		},
+	})
+}
+
+func writeErrors(w http.ResponseWriter, status int, title string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{
+			{"status": fmt.Sprint(status), "title": title},
+		},
+	})
+}
+
+type fakeStateVersion struct {
+	resource *stateVersionResource
+	state    []byte
+}
+
+func (f *fakeServer) handleLock(w http.ResponseWriter, workspaceID string, lock bool) {
+	ws := f.workspaceByID(workspaceID)
+	if ws == nil {
+		writeErrors(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if f.locked[workspaceID] == lock {
+		writeErrors(w, http.StatusConflict, "workspace lock is already in the requested state")
+		return
+	}
+
+	f.locked[workspaceID] = lock
+	writeData(w, http.StatusOK, ws)
+}
+
+func (f *fakeServer) handleCreateStateVersion(w http.ResponseWriter, r *http.Request, workspaceID string) {
+	if f.workspaceByID(workspaceID) == nil {
+		writeErrors(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if !f.locked[workspaceID] {
+		writeErrors(w, http.StatusConflict, "workspace must be locked")
+		return
+	}
+
+	var doc struct {
+		Data stateVersionResource `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	state, err := base64.StdEncoding.DecodeString(doc.Data.Attributes.State)
+	if err != nil {
+		writeErrors(w, http.StatusUnprocessableEntity, "state is not base64 encoded")
+		return
+	}
+
+	sum := md5.Sum(state)
+	if hex.EncodeToString(sum[:]) != doc.Data.Attributes.MD5 {
+		writeErrors(w, http.StatusUnprocessableEntity, "md5 does not match state")
+		return
+	}
+
+	attrs := doc.Data.Attributes
+	attrs.State = ""
+	version := &fakeStateVersion{
+		resource: &stateVersionResource{ID: f.id("sv"), Attributes: attrs},
+		state:    state,
+	}
+	f.states[workspaceID] = append(f.states[workspaceID], version)
+	writeData(w, http.StatusCreated, version.resource)
+}
@@ -0,0 +1,165 @@
+package tfe
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// StateVersion is a version of a workspace's state
+type StateVersion struct {
+	ID      string
+	Serial  int64
+	Lineage string
+	MD5     string
+}
+
+// StateVersionOptions are the settings for creating a state version
+type StateVersionOptions struct {
+	Serial  int64
+	Lineage string
+	MD5     string
+	// State is the raw state file, which is base64 encoded when it is sent
+	State []byte
+}
+
+type stateVersionAttributes struct {
+	Serial  int64  `json:"serial"`
+	Lineage string `json:"lineage,omitempty"`
+	MD5     string `json:"md5"`
+	State   string `json:"state,omitempty"`
+}
+
+type stateVersionResource struct {
+	ID         string                 `json:"id"`
+	Attributes stateVersionAttributes `json:"attributes"`
+}
+
+func (r *stateVersionResource) stateVersion() *StateVersion {
+	return &StateVersion{
+		ID:      r.ID,
+		Serial:  r.Attributes.Serial,
+		Lineage: r.Attributes.Lineage,
+		MD5:     r.Attributes.MD5,
+	}
+}
+
+// NewStateVersionOptions reads the serial and lineage from a state file and computes its checksum
+func NewStateVersionOptions(state []byte) (StateVersionOptions, error) {
+	var meta struct {
+		Serial  int64  `json:"serial"`
+		Lineage string `json:"lineage"`
+	}
+	if err := json.Unmarshal(state, &meta); err != nil {
+		return StateVersionOptions{}, fmt.Errorf("failed to parse state: %v", err)
+	}
+
+	sum := md5.Sum(state)
+	return StateVersionOptions{
+		Serial:  meta.Serial,
+		Lineage: meta.Lineage,
+		MD5:     hex.EncodeToString(sum[:]),
+		State:   state,
+	}, nil
+}
+
+// CreateStateVersion creates a state version in a workspace. The workspace must be locked.
+func (c *Client) CreateStateVersion(workspaceID string, options StateVersionOptions) (*StateVersion, error) {
+	var res stateVersionResource
+	err := c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/state-versions", escape(workspaceID)), data(resource{
+		Type: "state-versions",
+		Attributes: stateVersionAttributes{
+			Serial:  options.Serial,
+			Lineage: options.Lineage,
+			MD5:     options.MD5,
+			State:   base64.StdEncoding.EncodeToString(options.State),
+		},
+	}), &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.stateVersion(), nil
+}
+
+// CurrentStateVersion reads the latest state version of a workspace, returning ErrNotFound if it has no state
+func (c *Client) CurrentStateVersion(workspaceID string) (*StateVersion, error) {
+	var res stateVersionResource
+	if err := c.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/current-state-version", escape(workspaceID)), nil, &res); err != nil {
+		return nil, err
+	}
+	return res.stateVersion(), nil
+}
+
+// LockWorkspace locks a workspace so that no runs can change its state
+func (c *Client) LockWorkspace(workspaceID, reason string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/actions/lock", escape(workspaceID)), map[string]string{"reason": reason}, nil)
+}
+
+// UnlockWorkspace unlocks a workspace
+func (c *Client) UnlockWorkspace(workspaceID string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/actions/unlock", escape(workspaceID)), nil, nil)
+}
+
+// UploadState locks a workspace, creates a state version from a state file, and unlocks the workspace. It refuses to
+// replace existing state with a different lineage or a serial that is newer. If the workspace already has the state
+// file, such as from an earlier attempt, it returns the current state version and created is false.
+func (c *Client) UploadState(workspaceID string, state []byte) (sv *StateVersion, created bool, err error) {
+	options, err := NewStateVersionOptions(state)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := c.LockWorkspace(workspaceID, "Uploading state with terraform-cloud-migrate"); err != nil {
+		return nil, false, fmt.Errorf("failed to lock workspace: %v", err)
+	}
+
+	defer func() {
+		if uerr := c.UnlockWorkspace(workspaceID); uerr != nil && err == nil {
+			err = fmt.Errorf("failed to unlock workspace: %v", uerr)
+		}
+	}()
+
+	current, cerr := c.CurrentStateVersion(workspaceID)
+	if cerr != nil && cerr != ErrNotFound {
+		return nil, false, fmt.Errorf("failed to read current state: %v", cerr)
+	}
+	if cerr == nil {
+		uploaded, err := checkStateVersion(current, options)
+		if err != nil {
+			return nil, false, err
+		}
+		if uploaded {
+			return current, false, nil
+		}
+	}
+
+	sv, err = c.CreateStateVersion(workspaceID, options)
+	if err != nil {
+		return nil, false, err
+	}
+	return sv, true, nil
+}
+
+// checkStateVersion returns whether the current state version is already the state file, or an error if the state
+// file cannot replace it
+func checkStateVersion(current *StateVersion, options StateVersionOptions) (bool, error) {
+	if current.Lineage != "" && options.Lineage != "" && current.Lineage != options.Lineage {
+		return false, fmt.Errorf("workspace state has lineage %s, which does not match the uploaded state's lineage %s", current.Lineage, options.Lineage)
+	}
+
+	if current.Serial > options.Serial {
+		return false, fmt.Errorf("workspace state has serial %d, which is newer than the uploaded state's serial %d", current.Serial, options.Serial)
+	}
+
+	if current.Serial == options.Serial {
+		if current.MD5 == options.MD5 {
+			return true, nil
+		}
+		return false, fmt.Errorf("workspace state has serial %d, the same as the uploaded state, but different contents", current.Serial)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,153 @@
+package tfe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testState = `{
+  "version": 4,
+  "terraform_version": "0.12.24",
+  "serial": 7,
+  "lineage": "3f6b6f1e-5d1a-4f0e-9b0a-2a6c1c9f6a11",
+  "outputs": {},
+  "resources": []
+}
+`
+
+func TestNewStateVersionOptions(t *testing.T) {
+	options, err := NewStateVersionOptions([]byte(testState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(7), options.Serial)
+	assert.Equal(t, "3f6b6f1e-5d1a-4f0e-9b0a-2a6c1c9f6a11", options.Lineage)
+	assert.Len(t, options.MD5, 32)
+
+	_, err = NewStateVersionOptions([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestUploadState(t *testing.T) {
+	server := newFakeServer(t)
+
+	client := server.client()
+	ws, err := client.CreateWorkspace("org", WorkspaceOptions{Name: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CurrentStateVersion(ws.ID)
+	assert.Equal(t, ErrNotFound, err)
+
+	sv, created, err := client.UploadState(ws.ID, []byte(testState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, created)
+	assert.Equal(t, int64(7), sv.Serial)
+	assert.Equal(t, "3f6b6f1e-5d1a-4f0e-9b0a-2a6c1c9f6a11", sv.Lineage)
+	assert.Equal(t, []byte(testState), server.states[ws.ID][0].state)
+	assert.False(t, server.locked[ws.ID], "workspace is unlocked after upload")
+
+	current, err := client.CurrentStateVersion(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sv, current)
+}
+
+func TestUploadState_locked(t *testing.T) {
+	server := newFakeServer(t)
+
+	client := server.client()
+	ws, err := client.CreateWorkspace("org", WorkspaceOptions{Name: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.LockWorkspace(ws.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.UploadState(ws.ID, []byte(testState))
+	assert.EqualError(t, err, "failed to lock workspace: API request failed with status 409: workspace lock is already in the requested state")
+	assert.Empty(t, server.states[ws.ID])
+	assert.True(t, server.locked[ws.ID], "a lock held by someone else is not released")
+}
+
+func TestUploadState_existing(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		created bool
+		err     string
+	}{
+		{
+			name:    "newer",
+			state:   strings.Replace(testState, `"serial": 7`, `"serial": 8`, 1),
+			created: true,
+		},
+		{
+			name:  "already uploaded",
+			state: testState,
+		},
+		{
+			name:  "same serial",
+			state: strings.Replace(testState, `"outputs": {}`, `"outputs": {"id": {"value": "a", "type": "string"}}`, 1),
+			err:   "workspace state has serial 7, the same as the uploaded state, but different contents",
+		},
+		{
+			name:  "older",
+			state: strings.Replace(testState, `"serial": 7`, `"serial": 6`, 1),
+			err:   "workspace state has serial 7, which is newer than the uploaded state's serial 6",
+		},
+		{
+			name: "lineage",
+			state: strings.NewReplacer(
+				`"serial": 7`, `"serial": 8`,
+				"3f6b6f1e-5d1a-4f0e-9b0a-2a6c1c9f6a11", "9d1c2e3f-0000-4000-8000-000000000000",
+			).Replace(testState),
+			err: "workspace state has lineage 3f6b6f1e-5d1a-4f0e-9b0a-2a6c1c9f6a11, which does not match the uploaded state's lineage 9d1c2e3f-0000-4000-8000-000000000000",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFakeServer(t)
+
+			client := server.client()
+			ws, err := client.CreateWorkspace("org", WorkspaceOptions{Name: "app"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			first, _, err := client.UploadState(ws.ID, []byte(testState))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sv, created, err := client.UploadState(ws.ID, []byte(tc.state))
+			assert.False(t, server.locked[ws.ID], "workspace is unlocked after upload")
+			assert.Equal(t, tc.created, created)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Len(t, server.states[ws.ID], 1)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tc.created {
+				assert.Len(t, server.states[ws.ID], 2)
+			} else {
+				assert.Equal(t, first, sv)
+				assert.Len(t, server.states[ws.ID], 1)
+			}
+		})
+	}
+}
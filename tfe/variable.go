@@ -0,0 +1,138 @@
+package tfe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Variable categories
+const (
+	CategoryTerraform = "terraform"
+	CategoryEnv       = "env"
+)
+
+// Variable is a workspace variable. The values of sensitive variables are not returned by the API.
+type Variable struct {
+	ID        string
+	Key       string
+	Value     string
+	Category  string
+	HCL       bool
+	Sensitive bool
+}
+
+// VariableOptions are the settings for creating or updating a variable
+type VariableOptions struct {
+	Key       string
+	Value     string
+	Category  string
+	HCL       bool
+	Sensitive bool
+}
+
+type variableAttributes struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Category  string `json:"category"`
+	HCL       bool   `json:"hcl"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+type variableResource struct {
+	ID         string             `json:"id"`
+	Attributes variableAttributes `json:"attributes"`
+}
+
+func (r *variableResource) variable() *Variable {
+	return &Variable{
+		ID:        r.ID,
+		Key:       r.Attributes.Key,
+		Value:     r.Attributes.Value,
+		Category:  r.Attributes.Category,
+		HCL:       r.Attributes.HCL,
+		Sensitive: r.Attributes.Sensitive,
+	}
+}
+
+func (o VariableOptions) attributes() variableAttributes {
+	category := o.Category
+	if category == "" {
+		category = CategoryTerraform
+	}
+
+	return variableAttributes{
+		Key:       o.Key,
+		Value:     o.Value,
+		Category:  category,
+		HCL:       o.HCL,
+		Sensitive: o.Sensitive,
+	}
+}
+
+// Variables lists the variables of a workspace, following pagination
+func (c *Client) Variables(workspaceID string) ([]*Variable, error) {
+	var variables []*Variable
+	err := c.list(variablesPath(workspaceID), func(data json.RawMessage) error {
+		var res []variableResource
+		if err := json.Unmarshal(data, &res); err != nil {
+			return err
+		}
+
+		for i := range res {
+			variables = append(variables, res[i].variable())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// CreateVariable creates a variable in a workspace
+func (c *Client) CreateVariable(workspaceID string, options VariableOptions) (*Variable, error) {
+	var res variableResource
+	err := c.do(http.MethodPost, variablesPath(workspaceID), data(resource{
+		Type:       "vars",
+		Attributes: options.attributes(),
+	}), &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.variable(), nil
+}
+
+// UpdateVariable updates an existing workspace variable
+func (c *Client) UpdateVariable(workspaceID, variableID string, options VariableOptions) (*Variable, error) {
+	var res variableResource
+	err := c.do(http.MethodPatch, variablesPath(workspaceID)+"/"+escape(variableID), data(resource{
+		ID:         variableID,
+		Type:       "vars",
+		Attributes: options.attributes(),
+	}), &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.variable(), nil
+}
+
+// UpsertVariable creates a variable or updates the variable in existing with the same key and category. Existing
+// is the result of Variables for the workspace, so that it is listed once for many variables. It returns true if
+// the variable was created.
+func (c *Client) UpsertVariable(workspaceID string, existing []*Variable, options VariableOptions) (*Variable, bool, error) {
+	category := options.attributes().Category
+	for _, v := range existing {
+		if v.Key == options.Key && v.Category == category {
+			updated, err := c.UpdateVariable(workspaceID, v.ID, options)
+			return updated, false, err
+		}
+	}
+
+	created, err := c.CreateVariable(workspaceID, options)
+	return created, true, err
+}
+
+func variablesPath(workspaceID string) string {
+	return fmt.Sprintf("/workspaces/%s/vars", escape(workspaceID))
+}
@@ -0,0 +1,102 @@
+package tfe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertVariable(t *testing.T) {
+	server := newFakeServer(t)
+
+	client := server.client()
+	ws, err := client.CreateWorkspace("org", WorkspaceOptions{Name: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, created, err := client.UpsertVariable(ws.ID, nil, VariableOptions{
+		Key:   "tags",
+		Value: `{ team = "platform" }`,
+		HCL:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, created)
+	assert.Equal(t, &Variable{
+		ID:       v.ID,
+		Key:      "tags",
+		Value:    `{ team = "platform" }`,
+		Category: CategoryTerraform,
+		HCL:      true,
+	}, v)
+
+	updated, created, err := client.UpsertVariable(ws.ID, []*Variable{v}, VariableOptions{
+		Key:       "tags",
+		Value:     `{ team = "app" }`,
+		HCL:       true,
+		Sensitive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, created)
+	assert.Equal(t, v.ID, updated.ID)
+	assert.True(t, updated.Sensitive)
+
+	_, created, err = client.UpsertVariable(ws.ID, []*Variable{updated}, VariableOptions{
+		Key:      "tags",
+		Value:    "platform",
+		Category: CategoryEnv,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, created, "variables with the same key in another category are distinct")
+
+	variables, err := client.Variables(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, variables, 2)
+}
+
+func TestVariables_pagination(t *testing.T) {
+	server := newFakeServer(t)
+	server.pageSize = 2
+
+	client := server.client()
+	ws, err := client.CreateWorkspace("org", WorkspaceOptions{Name: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := client.CreateVariable(ws.ID, VariableOptions{Key: key, Value: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server.requests = nil
+	variables, err := client.Variables(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, len(variables))
+	for i, v := range variables {
+		keys[i] = v.Key
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.Len(t, server.requests, 2)
+}
+
+func TestVariables_workspaceNotFound(t *testing.T) {
+	server := newFakeServer(t)
+
+	_, err := server.client().Variables("ws-missing")
+	assert.Equal(t, ErrNotFound, err)
+}
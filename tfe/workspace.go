@@ -0,0 +1,134 @@
+package tfe
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Execution modes for workspaces
+const (
+	ExecutionModeRemote = "remote"
+	ExecutionModeLocal  = "local"
+	ExecutionModeAgent  = "agent"
+)
+
+// Workspace is a Terraform Cloud workspace
+type Workspace struct {
+	ID               string
+	Name             string
+	TerraformVersion string
+	ExecutionMode    string
+	AgentPoolID      string
+	WorkingDirectory string
+	AutoApply        bool
+	TagNames         []string
+}
+
+// WorkspaceOptions are the settings for creating or updating a workspace. Empty values are left unchanged.
+type WorkspaceOptions struct {
+	Name             string
+	TerraformVersion string
+	ExecutionMode    string
+	// AgentPoolID is required with the agent execution mode
+	AgentPoolID      string
+	WorkingDirectory string
+	AutoApply        *bool
+	TagNames         []string
+}
+
+type workspaceAttributes struct {
+	Name             string   `json:"name,omitempty"`
+	TerraformVersion string   `json:"terraform-version,omitempty"`
+	ExecutionMode    string   `json:"execution-mode,omitempty"`
+	AgentPoolID      string   `json:"agent-pool-id,omitempty"`
+	WorkingDirectory string   `json:"working-directory,omitempty"`
+	AutoApply        *bool    `json:"auto-apply,omitempty"`
+	TagNames         []string `json:"tag-names,omitempty"`
+}
+
+type workspaceResource struct {
+	ID         string              `json:"id"`
+	Attributes workspaceAttributes `json:"attributes"`
+}
+
+func (r *workspaceResource) workspace() *Workspace {
+	ws := &Workspace{
+		ID:               r.ID,
+		Name:             r.Attributes.Name,
+		TerraformVersion: r.Attributes.TerraformVersion,
+		ExecutionMode:    r.Attributes.ExecutionMode,
+		AgentPoolID:      r.Attributes.AgentPoolID,
+		WorkingDirectory: r.Attributes.WorkingDirectory,
+		TagNames:         r.Attributes.TagNames,
+	}
+	if r.Attributes.AutoApply != nil {
+		ws.AutoApply = *r.Attributes.AutoApply
+	}
+	return ws
+}
+
+func (o WorkspaceOptions) attributes() workspaceAttributes {
+	return workspaceAttributes{
+		Name:             o.Name,
+		TerraformVersion: o.TerraformVersion,
+		ExecutionMode:    o.ExecutionMode,
+		AgentPoolID:      o.AgentPoolID,
+		WorkingDirectory: o.WorkingDirectory,
+		AutoApply:        o.AutoApply,
+		TagNames:         o.TagNames,
+	}
+}
+
+// Workspace reads a workspace by name, returning ErrNotFound if it does not exist
+func (c *Client) Workspace(organization, name string) (*Workspace, error) {
+	var res workspaceResource
+	if err := c.do(http.MethodGet, workspacePath(organization, name), nil, &res); err != nil {
+		return nil, err
+	}
+	return res.workspace(), nil
+}
+
+// CreateWorkspace creates a workspace in the organization
+func (c *Client) CreateWorkspace(organization string, options WorkspaceOptions) (*Workspace, error) {
+	var res workspaceResource
+	err := c.do(http.MethodPost, fmt.Sprintf("/organizations/%s/workspaces", escape(organization)), data(resource{
+		Type:       "workspaces",
+		Attributes: options.attributes(),
+	}), &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.workspace(), nil
+}
+
+// UpdateWorkspace updates the settings of an existing workspace
+func (c *Client) UpdateWorkspace(organization, name string, options WorkspaceOptions) (*Workspace, error) {
+	var res workspaceResource
+	err := c.do(http.MethodPatch, workspacePath(organization, name), data(resource{
+		Type:       "workspaces",
+		Attributes: options.attributes(),
+	}), &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.workspace(), nil
+}
+
+// UpsertWorkspace creates a workspace or updates it if it already exists. It returns true if the workspace was created.
+func (c *Client) UpsertWorkspace(organization string, options WorkspaceOptions) (*Workspace, bool, error) {
+	_, err := c.Workspace(organization, options.Name)
+	if err == ErrNotFound {
+		ws, err := c.CreateWorkspace(organization, options)
+		return ws, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	ws, err := c.UpdateWorkspace(organization, options.Name, options)
+	return ws, false, err
+}
+
+func workspacePath(organization, name string) string {
+	return fmt.Sprintf("/organizations/%s/workspaces/%s", escape(organization), escape(name))
+}
@@ -0,0 +1,107 @@
+package tfe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_notFound(t *testing.T) {
+	server := newFakeServer(t)
+
+	_, err := server.client().Workspace("org", "missing")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestUpsertWorkspace(t *testing.T) {
+	server := newFakeServer(t)
+
+	client := server.client()
+	autoApply := true
+
+	ws, created, err := client.UpsertWorkspace("org", WorkspaceOptions{
+		Name:             "app-prod",
+		TerraformVersion: "0.12.24",
+		ExecutionMode:    ExecutionModeRemote,
+		WorkingDirectory: "infra/app",
+		AutoApply:        &autoApply,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, created)
+	assert.NotEmpty(t, ws.ID)
+	assert.Equal(t, &Workspace{
+		ID:               ws.ID,
+		Name:             "app-prod",
+		TerraformVersion: "0.12.24",
+		ExecutionMode:    ExecutionModeRemote,
+		WorkingDirectory: "infra/app",
+		AutoApply:        true,
+	}, ws)
+
+	updated, created, err := client.UpsertWorkspace("org", WorkspaceOptions{
+		Name:          "app-prod",
+		ExecutionMode: ExecutionModeLocal,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, created)
+	assert.Equal(t, ws.ID, updated.ID)
+	assert.Equal(t, ExecutionModeLocal, updated.ExecutionMode)
+	assert.Equal(t, "0.12.24", updated.TerraformVersion, "unset options are unchanged")
+	assert.True(t, updated.AutoApply)
+
+	assert.Equal(t, []string{
+		"GET /api/v2/organizations/org/workspaces/app-prod",
+		"POST /api/v2/organizations/org/workspaces",
+		"GET /api/v2/organizations/org/workspaces/app-prod",
+		"PATCH /api/v2/organizations/org/workspaces/app-prod",
+	}, server.requests)
+}
+
+func TestUpsertWorkspace_agent(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client()
+
+	_, _, err := client.UpsertWorkspace("org", WorkspaceOptions{
+		Name:          "app-prod",
+		ExecutionMode: ExecutionModeAgent,
+	})
+	assert.EqualError(t, err, "API request failed with status 422: Agent pool must be set when execution mode is agent")
+
+	ws, _, err := client.UpsertWorkspace("org", WorkspaceOptions{
+		Name:          "app-prod",
+		ExecutionMode: ExecutionModeAgent,
+		AgentPoolID:   "apool-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "apool-1", ws.AgentPoolID)
+
+	ws, _, err = client.UpsertWorkspace("org", WorkspaceOptions{
+		Name:          "app-prod",
+		ExecutionMode: ExecutionModeRemote,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, ws.AgentPoolID, "the agent pool is cleared when the execution mode changes")
+}
+
+func TestClient_apiError(t *testing.T) {
+	server := newFakeServer(t)
+
+	client := server.client()
+	client.Token = "wrong"
+
+	_, err := client.Workspace("org", "app")
+	if assert.IsType(t, &APIError{}, err) {
+		assert.Equal(t, 401, err.(*APIError).StatusCode)
+		assert.Equal(t, "API request failed with status 401: unauthorized", err.Error())
+	}
+}